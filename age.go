@@ -0,0 +1,224 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const (
+	// AgeKeyVariable names the environment variable holding age identities inline.
+	AgeKeyVariable = "PROJECT_TOML_AGE_KEY"
+	// AgeKeyFileVariable names the environment variable holding the path of an age
+	// identity file.
+	AgeKeyFileVariable = "PROJECT_TOML_AGE_KEY_FILE"
+	// ageValuePrefix marks a string value holding base64-encoded age ciphertext.
+	ageValuePrefix = "age:"
+	// ageKeyFileName is the default identity file below the user configuration directory.
+	ageKeyFileName = "age-keys.txt"
+)
+
+var (
+	// ErrAgeIdentityNotFound is returned when an age-encrypted document or value is read
+	// but no identity is configured.
+	ErrAgeIdentityNotFound = errors.New("no age identity available")
+	// ErrAgeDecrypt is returned when age-encrypted content cannot be decrypted.
+	ErrAgeDecrypt = errors.New("failed to decrypt age content")
+)
+
+// ageHeader starts every binary age file.
+var ageHeader = []byte("age-encryption.org/v1\n")
+
+// isAgeEncrypted reports whether content is an age file, binary or armored.
+func isAgeEncrypted(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+
+	return bytes.HasPrefix(trimmed, ageHeader) || bytes.HasPrefix(trimmed, []byte(armor.Header))
+}
+
+// EncryptAgeValue encrypts plaintext to the age recipient, e.g. "age1...", and returns
+// a string value that Load decrypts transparently when an identity is available.
+func EncryptAgeValue(plaintext, recipient string) (string, error) {
+	parsed, parseErr := age.ParseX25519Recipient(recipient)
+	if parseErr != nil {
+		return "", fmt.Errorf("failed to parse age recipient: %w", parseErr)
+	}
+
+	var ciphertext bytes.Buffer
+
+	writer, encryptErr := age.Encrypt(&ciphertext, parsed)
+	if encryptErr != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", encryptErr)
+	}
+
+	_, writeErr := io.WriteString(writer, plaintext)
+	if writeErr != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", writeErr)
+	}
+
+	closeErr := writer.Close()
+	if closeErr != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", closeErr)
+	}
+
+	return ageValuePrefix + base64.StdEncoding.EncodeToString(ciphertext.Bytes()), nil
+}
+
+// decryptAgeDocument decrypts an age-encrypted document.
+func decryptAgeDocument(content []byte) ([]byte, error) {
+	identities, identityErr := ageIdentities()
+	if identityErr != nil {
+		return nil, identityErr
+	}
+
+	var source io.Reader = bytes.NewReader(bytes.TrimSpace(content))
+	if !bytes.HasPrefix(bytes.TrimSpace(content), ageHeader) {
+		source = armor.NewReader(source)
+	}
+
+	return decryptAge(source, identities)
+}
+
+// decryptAgeValues returns table with every age: string value, at any depth, replaced by
+// its plaintext. The boolean result reports whether any value was encrypted.
+func decryptAgeValues(table map[string]any) (map[string]any, bool, error) {
+	decrypter := &ageValueDecrypter{}
+
+	decrypted, decryptErr := decrypter.value(table)
+	if decryptErr != nil {
+		return nil, false, decryptErr
+	}
+
+	result, _ := decrypted.(map[string]any)
+
+	return result, decrypter.identities != nil, nil
+}
+
+// ageValueDecrypter decrypts age: values, loading the identities on first use.
+type ageValueDecrypter struct {
+	identities []age.Identity
+}
+
+// value decrypts the age: strings within value.
+func (d *ageValueDecrypter) value(value any) (any, error) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, nested := range typed {
+			decrypted, nestedErr := d.value(nested)
+			if nestedErr != nil {
+				return nil, fmt.Errorf("%s: %w", key, nestedErr)
+			}
+
+			typed[key] = decrypted
+		}
+
+		return typed, nil
+	case []any:
+		for index, element := range typed {
+			decrypted, elementErr := d.value(element)
+			if elementErr != nil {
+				return nil, elementErr
+			}
+
+			typed[index] = decrypted
+		}
+
+		return typed, nil
+	case string:
+		encoded, encrypted := strings.CutPrefix(typed, ageValuePrefix)
+		if !encrypted {
+			return typed, nil
+		}
+
+		return d.decrypt(encoded)
+	default:
+		return value, nil
+	}
+}
+
+// decrypt decodes and decrypts a single age: value.
+func (d *ageValueDecrypter) decrypt(encoded string) (string, error) {
+	if d.identities == nil {
+		identities, identityErr := ageIdentities()
+		if identityErr != nil {
+			return "", identityErr
+		}
+
+		d.identities = identities
+	}
+
+	ciphertext, decodeErr := base64.StdEncoding.DecodeString(encoded)
+	if decodeErr != nil {
+		return "", fmt.Errorf("%w: %w", ErrAgeDecrypt, decodeErr)
+	}
+
+	plaintext, decryptErr := decryptAge(bytes.NewReader(ciphertext), d.identities)
+	if decryptErr != nil {
+		return "", decryptErr
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptAge decrypts the age ciphertext read from source.
+func decryptAge(source io.Reader, identities []age.Identity) ([]byte, error) {
+	reader, decryptErr := age.Decrypt(source, identities...)
+	if decryptErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAgeDecrypt, decryptErr)
+	}
+
+	plaintext, readErr := io.ReadAll(reader)
+	if readErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAgeDecrypt, readErr)
+	}
+
+	return plaintext, nil
+}
+
+// ageIdentities loads the identities from AgeKeyVariable, the file named by
+// AgeKeyFileVariable, or the default identity file in the user configuration
+// directory, in that order.
+func ageIdentities() ([]age.Identity, error) {
+	if inline := os.Getenv(AgeKeyVariable); inline != "" {
+		return parseAgeIdentities(strings.NewReader(inline), AgeKeyVariable)
+	}
+
+	path := os.Getenv(AgeKeyFileVariable)
+	if path == "" {
+		configDir, configDirErr := os.UserConfigDir()
+		if configDirErr != nil {
+			return nil, ErrAgeIdentityNotFound
+		}
+
+		path = filepath.Join(configDir, userConfigDirectory, ageKeyFileName)
+	}
+
+	keys, readErr := os.ReadFile(path)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrAgeIdentityNotFound, path)
+	}
+
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read age identity file: %w", readErr)
+	}
+
+	return parseAgeIdentities(bytes.NewReader(keys), path)
+}
+
+// parseAgeIdentities parses the identities read from source, named origin in errors.
+func parseAgeIdentities(source io.Reader, origin string) ([]age.Identity, error) {
+	identities, parseErr := age.ParseIdentities(source)
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse age identities from %s: %w", origin, parseErr)
+	}
+
+	return identities, nil
+}
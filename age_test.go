@@ -0,0 +1,157 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ageIdentity returns a new age identity and makes it the one Load uses.
+func ageIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	identity, generateErr := age.GenerateX25519Identity()
+	require.NoError(t, generateErr)
+
+	t.Setenv(AgeKeyVariable, identity.String())
+
+	return identity
+}
+
+// ageEncrypt encrypts plaintext to recipient, armored when armored is true.
+func ageEncrypt(t *testing.T, plaintext string, recipient age.Recipient, armored bool) string {
+	t.Helper()
+
+	var (
+		ciphertext bytes.Buffer
+		output     io.WriteCloser = nopWriteCloser{&ciphertext}
+	)
+
+	if armored {
+		output = armor.NewWriter(&ciphertext)
+	}
+
+	writer, encryptErr := age.Encrypt(output, recipient)
+	require.NoError(t, encryptErr)
+
+	_, writeErr := io.WriteString(writer, plaintext)
+	require.NoError(t, writeErr)
+	require.NoError(t, writer.Close())
+	require.NoError(t, output.Close())
+
+	return ciphertext.String()
+}
+
+// nopWriteCloser adds a Close method that does nothing to a writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestLoadDecryptsAgeDocuments(t *testing.T) {
+	identity := ageIdentity(t)
+
+	for name, armored := range map[string]bool{"binary": false, "armored": true} {
+		t.Run(name, func(t *testing.T) {
+			localDocument(t, ageEncrypt(t, "[service]\nname = \"tts\"\nport = 8080\n", identity.Recipient(), armored))
+
+			var config testConfig
+
+			require.NoError(t, Load(&config, newTestLogger(t)))
+			assert.Equal(t, "tts", config.Service.Name)
+			assert.Equal(t, 8080, config.Service.Port)
+		})
+	}
+}
+
+func TestLoadDecryptsAgeValues(t *testing.T) {
+	identity := ageIdentity(t)
+
+	name, encryptErr := EncryptAgeValue("tts", identity.Recipient().String())
+	require.NoError(t, encryptErr)
+	require.True(t, strings.HasPrefix(name, ageValuePrefix))
+
+	localDocument(t, "[service]\nname = \""+name+"\"\nport = 8080\n")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, "tts", config.Service.Name)
+
+	keyFile := filepath.Join(t.TempDir(), ageKeyFileName)
+	require.NoError(t, os.WriteFile(keyFile, []byte("# created: today\n"+identity.String()+"\n"), 0o600))
+	t.Setenv(AgeKeyVariable, "")
+	t.Setenv(AgeKeyFileVariable, keyFile)
+
+	var fromFile testConfig
+
+	require.NoError(t, Load(&fromFile, newTestLogger(t)))
+	assert.Equal(t, "tts", fromFile.Service.Name)
+}
+
+func TestLoadRejectsUndecryptableAgeContent(t *testing.T) {
+	identity := ageIdentity(t)
+	other, generateErr := age.GenerateX25519Identity()
+	require.NoError(t, generateErr)
+
+	value, encryptErr := EncryptAgeValue("tts", identity.Recipient().String())
+	require.NoError(t, encryptErr)
+
+	ciphertext, decodeErr := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ageValuePrefix))
+	require.NoError(t, decodeErr)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	tampered := ageValuePrefix + base64.StdEncoding.EncodeToString(ciphertext)
+
+	tests := map[string]struct {
+		content  string
+		identity string
+		want     error
+	}{
+		"value for another identity": {
+			content: "name = \"" + value + "\"\n", identity: other.String(), want: ErrAgeDecrypt,
+		},
+		"document for another identity": {
+			content:  ageEncrypt(t, "name = \"tts\"\n", identity.Recipient(), true),
+			identity: other.String(), want: ErrAgeDecrypt,
+		},
+		"tampered value":         {content: "name = \"" + tampered + "\"\n", identity: identity.String(), want: ErrAgeDecrypt},
+		"malformed value":        {content: "name = \"age:not base64!\"\n", identity: identity.String(), want: ErrAgeDecrypt},
+		"value without identity": {content: "name = \"" + value + "\"\n", want: ErrAgeIdentityNotFound},
+		"document without identity": {
+			content: ageEncrypt(t, "name = \"tts\"\n", identity.Recipient(), false), want: ErrAgeIdentityNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(AgeKeyVariable, test.identity)
+			t.Setenv(AgeKeyFileVariable, filepath.Join(t.TempDir(), "missing.txt"))
+			localDocument(t, test.content)
+
+			var config map[string]any
+
+			require.ErrorIs(t, Load(&config, newTestLogger(t)), test.want)
+		})
+	}
+}
+
+func TestEncryptAgeValueRejectsBadRecipients(t *testing.T) {
+	t.Parallel()
+
+	_, encryptErr := EncryptAgeValue("tts", "not a recipient")
+	require.Error(t, encryptErr)
+}
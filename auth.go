@@ -0,0 +1,159 @@
+package configurator
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// TokenVariable names the environment variable holding a bearer token for fetching
+	// configuration, used when WithBearerToken is not given.
+	TokenVariable = "PROJECT_TOML_TOKEN"
+	// UsernameVariable names the environment variable holding the basic authentication
+	// user name, used when WithBasicAuth is not given.
+	UsernameVariable = "PROJECT_TOML_USERNAME"
+	// PasswordVariable names the environment variable holding the basic authentication
+	// password.
+	PasswordVariable = "PROJECT_TOML_PASSWORD"
+	// CredentialHostsVariable names the environment variable holding a comma-separated
+	// list of additional hosts that receive credentials, used when WithCredentialHosts is
+	// not given.
+	CredentialHostsVariable = "PROJECT_TOML_CREDENTIAL_HOSTS"
+)
+
+// WithBearerToken sends token in an Authorization: Bearer header with configuration
+// requests to the PROJECT_TOML host and the credential hosts.
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.bearerToken = token
+	}
+}
+
+// WithBasicAuth sends username and password with configuration requests to the
+// PROJECT_TOML host and the credential hosts using HTTP basic authentication.
+func WithBasicAuth(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithHeader adds a header to configuration requests to the PROJECT_TOML host and the
+// credential hosts, e.g. an API key header.
+func WithHeader(name, value string) Option {
+	return func(o *options) {
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+
+		o.headers.Add(name, value)
+	}
+}
+
+// WithCredentialHosts names hosts other than the PROJECT_TOML host that receive the
+// configured headers, credentials, OAuth 2.0 tokens, and SigV4 signatures, e.g. the host
+// serving shared includes. An entry of the form *.example.com matches any subdomain of
+// example.com. Requests to any other host are sent without them.
+func WithCredentialHosts(hosts ...string) Option {
+	return func(o *options) {
+		o.credentialHosts = hosts
+	}
+}
+
+// sendsCredentials reports whether a request for source may carry credentials: source
+// must be served by the PROJECT_TOML host, or the same Unix domain socket, or by one of
+// the credential hosts.
+func (o *options) sendsCredentials(source string) bool {
+	origin, isRemote := sourceOrigin(source)
+	if !isRemote {
+		return false
+	}
+
+	if projectOrigin, projectRemote := sourceOrigin(os.Getenv("PROJECT_TOML")); projectRemote && origin == projectOrigin {
+		return true
+	}
+
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil || parsed.Scheme == unixScheme {
+		return false
+	}
+
+	return matchesHost(parsed.Hostname(), o.credentialHosts)
+}
+
+// defaultPorts maps URL schemes to the port a URL without one is fetched from.
+var defaultPorts = map[string]string{"http": "80", httpsScheme: "443"}
+
+// sourceOrigin returns the lower-cased host and port, or the socket of a unix:// URL,
+// that a remote source is fetched from. The boolean result is false for local files.
+func sourceOrigin(source string) (string, bool) {
+	_, socket, isSocket, socketErr := splitSocketURL(source)
+	if socketErr != nil {
+		return "", false
+	}
+
+	if isSocket {
+		return unixScheme + "://" + socket, true
+	}
+
+	if _, isLocal := localPath(source); isLocal {
+		return "", false
+	}
+
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil || parsed.Host == "" {
+		return "", false
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = defaultPorts[parsed.Scheme]
+	}
+
+	return net.JoinHostPort(strings.ToLower(parsed.Hostname()), port), true
+}
+
+// authorize adds the User-Agent to req and, when credentials is true, the configured
+// headers and credentials. A User-Agent given with WithHeader replaces the configured
+// one, and a bearer token takes precedence over basic authentication.
+func (o *options) authorize(req *http.Request, credentials bool) {
+	if credentials {
+		for name, values := range o.headers {
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+	}
+
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", o.userAgent)
+	}
+
+	if !credentials {
+		return
+	}
+
+	switch {
+	case o.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+o.bearerToken)
+	case o.username != "":
+		req.SetBasicAuth(o.username, o.password)
+	}
+}
+
+// stripCredentials removes the configured headers and credentials from a redirected
+// request for a host that may not receive them.
+func (o *options) stripCredentials(req *http.Request) {
+	if o.sendsCredentials(req.URL.String()) {
+		return
+	}
+
+	for name := range o.headers {
+		req.Header.Del(name)
+	}
+
+	req.Header.Del("Authorization")
+}
@@ -0,0 +1,109 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headerRecorder records the Authorization and X-Api-Key headers of each request by path.
+type headerRecorder struct {
+	mu      sync.Mutex
+	headers map[string][2]string
+}
+
+// record stores the credentials req carried.
+func (h *headerRecorder) record(req *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.headers == nil {
+		h.headers = make(map[string][2]string)
+	}
+
+	h.headers[req.URL.Path] = [2]string{req.Header.Get("Authorization"), req.Header.Get("X-Api-Key")}
+}
+
+// get returns the credentials recorded for path.
+func (h *headerRecorder) get(path string) [2]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.headers[path]
+}
+
+// recordingServer serves documents, recording the credentials of each request, and
+// redirects /moved/<path> to <path> on redirectTarget when that is set.
+func recordingServer(t *testing.T, recorder *headerRecorder, documents map[string]string, redirectTarget *string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder.record(r)
+
+		if moved, isMoved := strings.CutPrefix(r.URL.Path, "/moved"); isMoved && redirectTarget != nil {
+			http.Redirect(w, r, *redirectTarget+moved, http.StatusFound)
+
+			return
+		}
+
+		content, found := documents[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCredentialsStayWithTheConfigurationHost(t *testing.T) {
+	var (
+		baseHeaders, thirdHeaders headerRecorder
+		thirdURL                  string
+	)
+
+	third := recordingServer(t, &thirdHeaders, map[string]string{"/nats.toml": "[nats]\nurl = \"nats://a\"\n"}, nil)
+	thirdURL = third.URL
+
+	base := recordingServer(t, &baseHeaders, map[string]string{
+		"/project.toml": "include = [\"tts.toml\", \"/moved/nats.toml\", \"" + third.URL + "/nats.toml\"]\n",
+		"/tts.toml":     "[service]\nname = \"tts\"\n",
+	}, &thirdURL)
+	t.Setenv("PROJECT_TOML", base.URL+"/project.toml")
+
+	credentials := []Option{WithBearerToken("secret"), WithHeader("X-Api-Key", "key")}
+	sent := [2]string{"Bearer secret", "key"}
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), credentials...))
+	assert.Equal(t, sent, baseHeaders.get("/project.toml"))
+	assert.Equal(t, sent, baseHeaders.get("/tts.toml"))
+	assert.Equal(t, sent, baseHeaders.get("/moved/nats.toml"))
+	assert.Equal(t, [2]string{}, thirdHeaders.get("/nats.toml"))
+
+	require.NoError(t, Load(&config, newTestLogger(t), append(credentials, WithCredentialHosts("127.0.0.1"))...))
+	assert.Equal(t, sent, thirdHeaders.get("/nats.toml"))
+}
+
+func TestSendsCredentials(t *testing.T) {
+	t.Setenv("PROJECT_TOML", "https://cfg.example/project.toml")
+
+	settings := newOptions([]Option{WithCredentialHosts("*.trusted.example")})
+
+	assert.True(t, settings.sendsCredentials("https://CFG.example/other.toml"))
+	assert.True(t, settings.sendsCredentials("https://cfg.example:443/other.toml#sha256=00"))
+	assert.False(t, settings.sendsCredentials("https://cfg.example:8443/other.toml"))
+	assert.False(t, settings.sendsCredentials("https://evil.example/project.toml"))
+	assert.True(t, settings.sendsCredentials("https://shared.trusted.example/nats.toml"))
+	assert.False(t, settings.sendsCredentials("/etc/book-expert/project.toml"))
+}
@@ -0,0 +1,359 @@
+package configurator
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// containerCredentialsHost serves the credentials of ECS tasks at
+	// AWS_CONTAINER_CREDENTIALS_RELATIVE_URI.
+	containerCredentialsHost = "http://169.254.170.2"
+	// defaultIMDSEndpoint is the EC2 instance metadata service, used when
+	// AWS_EC2_METADATA_SERVICE_ENDPOINT is unset.
+	defaultIMDSEndpoint = "http://169.254.169.254"
+	// imdsTokenTTL is the lifetime, in seconds, requested for IMDSv2 session tokens.
+	imdsTokenTTL = "21600"
+	// imdsTimeout bounds the instance metadata requests, so hosts outside EC2 give up
+	// quickly.
+	imdsTimeout = 2 * time.Second
+	// stsVersion is the STS API version AssumeRoleWithWebIdentity is called with.
+	stsVersion = "2011-06-15"
+	// awsCredentialsMargin is how long before their expiry temporary credentials are
+	// replaced.
+	awsCredentialsMargin = time.Minute
+)
+
+// allowedContainerHosts are the hosts AWS_CONTAINER_CREDENTIALS_FULL_URI may name over
+// plain HTTP besides loopback addresses: the ECS and EKS Pod Identity agents.
+var allowedContainerHosts = []string{"169.254.170.2", "169.254.170.23", "fd00:ec2::23"}
+
+// metadataClient fetches temporary credentials. It bypasses proxies and the internal
+// address block, since the credential endpoints are link-local by design.
+var metadataClient = func() *http.Client {
+	baseTransport, _ := http.DefaultTransport.(*http.Transport)
+	transport := baseTransport.Clone()
+	transport.Proxy = nil
+
+	return &http.Client{Transport: transport}
+}()
+
+// temporaryCredentials holds the last temporary credentials obtained from web identity,
+// the container endpoint, or the instance metadata service, by the source they came
+// from.
+var temporaryCredentials = struct {
+	mu          sync.Mutex
+	source      string
+	credentials awsCredentials
+}{}
+
+// remoteAWSCredentials obtains temporary credentials the way the AWS SDKs do after the
+// environment and the shared credentials file: from a web identity token, from the ECS
+// or EKS Pod Identity container endpoint, and finally from the EC2 instance metadata
+// service. Credentials are cached until shortly before they expire.
+func remoteAWSCredentials(ctx context.Context, timeout time.Duration) (awsCredentials, error) {
+	source, fetch := awsCredentialSource(timeout)
+
+	temporaryCredentials.mu.Lock()
+	defer temporaryCredentials.mu.Unlock()
+
+	cached := temporaryCredentials.credentials
+	if temporaryCredentials.source == source && time.Now().Before(cached.expiry.Add(-awsCredentialsMargin)) {
+		return cached, nil
+	}
+
+	credentials, fetchErr := fetch(ctx)
+	if errors.Is(fetchErr, ErrAWSCredentialsNotFound) {
+		return awsCredentials{}, fmt.Errorf("%s: %w", source, fetchErr)
+	}
+
+	if fetchErr != nil {
+		return awsCredentials{}, fmt.Errorf("%w: %s: %w", ErrAWSCredentialsNotFound, source, fetchErr)
+	}
+
+	temporaryCredentials.source, temporaryCredentials.credentials = source, credentials
+
+	return credentials, nil
+}
+
+// awsCredentialSource returns the first configured source of temporary credentials and
+// the function that fetches them.
+func awsCredentialSource(timeout time.Duration) (string, func(context.Context) (awsCredentials, error)) {
+	tokenFile, roleARN := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN")
+	if tokenFile != "" && roleARN != "" {
+		return "web identity " + roleARN, func(ctx context.Context) (awsCredentials, error) {
+			return webIdentityCredentials(ctx, timeout, tokenFile, roleARN)
+		}
+	}
+
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		return "container " + relative, func(ctx context.Context) (awsCredentials, error) {
+			return containerCredentials(ctx, timeout, containerCredentialsHost+relative)
+		}
+	}
+
+	if full := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); full != "" {
+		return "container " + full, func(ctx context.Context) (awsCredentials, error) {
+			return containerCredentials(ctx, timeout, full)
+		}
+	}
+
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultIMDSEndpoint
+	}
+
+	if disabled, _ := strconv.ParseBool(os.Getenv("AWS_EC2_METADATA_DISABLED")); disabled {
+		return "instance metadata", func(context.Context) (awsCredentials, error) {
+			return awsCredentials{}, fmt.Errorf("%w: disabled by AWS_EC2_METADATA_DISABLED", ErrAWSCredentialsNotFound)
+		}
+	}
+
+	return "instance metadata " + endpoint, func(ctx context.Context) (awsCredentials, error) {
+		return instanceCredentials(ctx, strings.TrimSuffix(endpoint, "/"))
+	}
+}
+
+// webIdentityCredentials exchanges the web identity token in tokenFile, as mounted into
+// EKS pods with IAM roles for service accounts, for credentials of roleARN through STS
+// AssumeRoleWithWebIdentity.
+func webIdentityCredentials(ctx context.Context, timeout time.Duration, tokenFile, roleARN string) (awsCredentials, error) {
+	token, readErr := os.ReadFile(tokenFile)
+	if readErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to read web identity token: %w", readErr)
+	}
+
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = "configurator-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_STS")
+	if endpoint == "" {
+		endpoint = "https://sts." + awsRegion("") + ".amazonaws.com"
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {stsVersion},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", strings.NewReader(form.Encode()))
+	if newRequestErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create HTTP request: %w", newRequestErr)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, exchangeErr := exchangeMetadata(req)
+	if exchangeErr != nil {
+		return awsCredentials{}, exchangeErr
+	}
+
+	var response struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+
+	unmarshalErr := xml.Unmarshal(body, &response)
+	if unmarshalErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode STS response: %w", unmarshalErr)
+	}
+
+	return checkedCredentials(awsCredentials{
+		accessKeyID:     response.Credentials.AccessKeyID,
+		secretAccessKey: response.Credentials.SecretAccessKey,
+		sessionToken:    response.Credentials.SessionToken,
+		expiry:          response.Credentials.Expiration,
+	})
+}
+
+// containerCredentials reads the credentials served to ECS tasks and EKS Pod Identity
+// pods at endpoint, authorized by AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE or
+// AWS_CONTAINER_AUTHORIZATION_TOKEN when set.
+func containerCredentials(ctx context.Context, timeout time.Duration, endpoint string) (awsCredentials, error) {
+	checkErr := checkContainerEndpoint(endpoint)
+	if checkErr != nil {
+		return awsCredentials{}, checkErr
+	}
+
+	authorization := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		token, readErr := os.ReadFile(tokenFile)
+		if readErr != nil {
+			return awsCredentials{}, fmt.Errorf("failed to read container authorization token: %w", readErr)
+		}
+
+		authorization = strings.TrimSpace(string(token))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if newRequestErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create HTTP request: %w", newRequestErr)
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return fetchCredentialsJSON(req)
+}
+
+// checkContainerEndpoint refuses a container credentials endpoint that would send the
+// authorization token in plain text off the machine: plain HTTP is only allowed to
+// loopback addresses and the ECS and EKS agents.
+func checkContainerEndpoint(endpoint string) error {
+	parsed, parseErr := url.Parse(endpoint)
+	if parseErr != nil {
+		return fmt.Errorf("%w: invalid container credentials URI: %w", ErrAWSCredentialsNotFound, parseErr)
+	}
+
+	if parsed.Scheme == httpsScheme {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	for _, allowed := range allowedContainerHosts {
+		if ip := net.ParseIP(host); ip != nil && ip.Equal(net.ParseIP(allowed)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: container credentials URI %s must use HTTPS or a loopback or agent address",
+		ErrAWSCredentialsNotFound, parsed.Redacted())
+}
+
+// instanceCredentials reads the credentials of the instance profile role from the EC2
+// instance metadata service at endpoint, using an IMDSv2 session token.
+func instanceCredentials(ctx context.Context, endpoint string) (awsCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, imdsTimeout)
+	defer cancel()
+
+	tokenRequest, newTokenRequestErr := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if newTokenRequestErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create HTTP request: %w", newTokenRequestErr)
+	}
+
+	tokenRequest.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", imdsTokenTTL)
+
+	token, tokenErr := exchangeMetadata(tokenRequest)
+	if tokenErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to get IMDSv2 token: %w", tokenErr)
+	}
+
+	credentialsURL := endpoint + "/latest/meta-data/iam/security-credentials/"
+
+	roleRequest, newRoleRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, credentialsURL, nil)
+	if newRoleRequestErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create HTTP request: %w", newRoleRequestErr)
+	}
+
+	roleRequest.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+
+	roles, rolesErr := exchangeMetadata(roleRequest)
+	if rolesErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to list instance profile roles: %w", rolesErr)
+	}
+
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return awsCredentials{}, fmt.Errorf("%w: no instance profile role", ErrAWSCredentialsNotFound)
+	}
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, credentialsURL+url.PathEscape(role), nil)
+	if newRequestErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create HTTP request: %w", newRequestErr)
+	}
+
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+
+	return fetchCredentialsJSON(req)
+}
+
+// fetchCredentialsJSON sends req and decodes the JSON credentials document the
+// container endpoints and the instance metadata service return.
+func fetchCredentialsJSON(req *http.Request) (awsCredentials, error) {
+	body, exchangeErr := exchangeMetadata(req)
+	if exchangeErr != nil {
+		return awsCredentials{}, exchangeErr
+	}
+
+	var document struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+
+	unmarshalErr := json.Unmarshal(body, &document)
+	if unmarshalErr != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode credentials: %w", unmarshalErr)
+	}
+
+	return checkedCredentials(awsCredentials{
+		accessKeyID:     document.AccessKeyID,
+		secretAccessKey: document.SecretAccessKey,
+		sessionToken:    document.Token,
+		expiry:          document.Expiration,
+	})
+}
+
+// checkedCredentials returns credentials unless they lack keys.
+func checkedCredentials(credentials awsCredentials) (awsCredentials, error) {
+	if credentials.accessKeyID == "" || credentials.secretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("%w: response has no keys", ErrAWSCredentialsNotFound)
+	}
+
+	return credentials, nil
+}
+
+// exchangeMetadata sends req with metadataClient and returns the body of its successful
+// response.
+func exchangeMetadata(req *http.Request) ([]byte, error) {
+	resp, doRequestErr := metadataClient.Do(req)
+	if doRequestErr != nil {
+		return nil, fmt.Errorf("failed to execute HTTP request: %w", doRequestErr)
+	}
+
+	body, processResponseErr := processResponse(resp, DefaultMaxBodySize)
+
+	closeErr := resp.Body.Close()
+	if processResponseErr != nil {
+		return nil, processResponseErr
+	}
+
+	if closeErr != nil {
+		return nil, fmt.Errorf("failed to close response body: %w", closeErr)
+	}
+
+	return body, nil
+}
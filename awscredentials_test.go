@@ -0,0 +1,219 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearAWSEnvironment unsets every source of AWS credentials for the test.
+func clearAWSEnvironment(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ROLE_SESSION_NAME", "AWS_ENDPOINT_URL_STS",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+		"AWS_EC2_METADATA_SERVICE_ENDPOINT",
+	} {
+		t.Setenv(name, "")
+	}
+
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+// credentialsJSON is the credentials document of the container and instance metadata
+// endpoints for key.
+func credentialsJSON(key string) string {
+	return fmt.Sprintf(`{"Code":"Success","AccessKeyId":%q,"SecretAccessKey":"secret","Token":"session","Expiration":%q}`,
+		key, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+}
+
+// imdsServer serves instance profile credentials over IMDSv2, refusing requests without
+// the session token, and counts the credential requests.
+func imdsServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			if r.Header.Get("X-Aws-Ec2-Metadata-Token-Ttl-Seconds") == "" {
+				http.Error(w, "missing TTL", http.StatusBadRequest)
+
+				return
+			}
+
+			_, _ = w.Write([]byte("imds-token"))
+
+			return
+		}
+
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			_, _ = w.Write([]byte("config-reader\n"))
+		case "/latest/meta-data/iam/security-credentials/config-reader":
+			requests.Add(1)
+			_, _ = w.Write([]byte(credentialsJSON("INSTANCEKEY")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestInstanceMetadataCredentials(t *testing.T) {
+	clearAWSEnvironment(t)
+
+	server, requests := imdsServer(t)
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL+"/")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+
+	credentials, loadErr := loadAWSCredentials(context.Background(), time.Second)
+	require.NoError(t, loadErr)
+	assert.Equal(t, "INSTANCEKEY", credentials.accessKeyID)
+	assert.Equal(t, "secret", credentials.secretAccessKey)
+	assert.Equal(t, "session", credentials.sessionToken)
+
+	_, cachedErr := loadAWSCredentials(context.Background(), time.Second)
+	require.NoError(t, cachedErr)
+	assert.Equal(t, int64(1), requests.Load(), "temporary credentials are cached until they expire")
+
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	_, disabledErr := loadAWSCredentials(context.Background(), time.Second)
+	require.ErrorIs(t, disabledErr, ErrAWSCredentialsNotFound)
+}
+
+func TestContainerCredentials(t *testing.T) {
+	clearAWSEnvironment(t)
+
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(credentialsJSON("CONTAINERKEY")))
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("pod-identity-token\n"), 0o600))
+
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/v1/credentials")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", tokenFile)
+
+	credentials, loadErr := loadAWSCredentials(context.Background(), time.Second)
+	require.NoError(t, loadErr)
+	assert.Equal(t, "CONTAINERKEY", credentials.accessKeyID)
+	assert.Equal(t, "session", credentials.sessionToken)
+	assert.Equal(t, "pod-identity-token", authorization)
+}
+
+func TestContainerCredentialsRefusePlainHTTPToOtherHosts(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, checkContainerEndpoint("http://127.0.0.1:8080/creds"))
+	require.NoError(t, checkContainerEndpoint("http://169.254.170.23/v1/credentials"))
+	require.NoError(t, checkContainerEndpoint("http://[fd00:ec2::23]/v1/credentials"))
+	require.NoError(t, checkContainerEndpoint("https://creds.example.com/v1"))
+	require.ErrorIs(t, checkContainerEndpoint("http://creds.example.com/v1"), ErrAWSCredentialsNotFound)
+}
+
+func TestWebIdentityCredentials(t *testing.T) {
+	clearAWSEnvironment(t)
+
+	var form map[string][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ParseForm() != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+
+			return
+		}
+
+		form = r.PostForm
+		_, _ = fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>WEBIDENTITYKEY</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("service-account-jwt"), 0o600))
+
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/config-reader")
+	t.Setenv("AWS_ROLE_SESSION_NAME", "tts")
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	credentials, loadErr := loadAWSCredentials(context.Background(), time.Second)
+	require.NoError(t, loadErr)
+	assert.Equal(t, "WEBIDENTITYKEY", credentials.accessKeyID)
+	assert.Equal(t, "session", credentials.sessionToken)
+	assert.Equal(t, []string{"AssumeRoleWithWebIdentity"}, form["Action"])
+	assert.Equal(t, []string{"arn:aws:iam::123456789012:role/config-reader"}, form["RoleArn"])
+	assert.Equal(t, []string{"tts"}, form["RoleSessionName"])
+	assert.Equal(t, []string{"service-account-jwt"}, form["WebIdentityToken"])
+}
+
+func TestExplicitProfileDoesNotFallBack(t *testing.T) {
+	clearAWSEnvironment(t)
+
+	server, requests := imdsServer(t)
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_PROFILE", "deploy")
+
+	_, loadErr := loadAWSCredentials(context.Background(), time.Second)
+	require.ErrorIs(t, loadErr, ErrAWSCredentialsNotFound)
+	assert.Zero(t, requests.Load())
+}
+
+func TestLoadSignsWithInstanceCredentials(t *testing.T) {
+	clearAWSEnvironment(t)
+
+	metadata, _ := imdsServer(t)
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", metadata.URL)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+
+	var authorization, securityToken string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, securityToken = r.Header.Get("Authorization"), r.Header.Get("X-Amz-Security-Token")
+		_, _ = w.Write([]byte("[service]\nname = \"tts\"\n"))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithSigV4("eu-west-1")))
+	assert.Contains(t, authorization, "Credential=INSTANCEKEY/")
+	assert.Equal(t, "session", securityToken)
+}
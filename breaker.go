@@ -0,0 +1,62 @@
+package configurator
+
+import "time"
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failed polls that opens the
+	// circuit breaker of a Watch.
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is how long an open circuit breaker skips polls.
+	DefaultBreakerCooldown = 5 * time.Minute
+)
+
+// WithCircuitBreaker makes a polling Watch stop fetching for cooldown after threshold
+// consecutive failures, so a struggling configuration host is not hammered by every
+// service at once. A threshold of zero disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breakerThreshold = threshold
+		o.breakerCooldown = cooldown
+	}
+}
+
+// circuitBreaker tracks consecutive fetch failures of a polling Watch. While it is open,
+// scheduled polls are skipped; the first poll after the cooldown is a trial whose
+// failure opens it again.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// newCircuitBreaker returns a closed breaker for settings.
+func newCircuitBreaker(settings *options) *circuitBreaker {
+	return &circuitBreaker{threshold: settings.breakerThreshold, cooldown: settings.breakerCooldown}
+}
+
+// allow reports whether a scheduled poll may fetch at now.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	return !now.Before(b.openUntil)
+}
+
+// record notes the outcome of a fetch made at now. A Retry-After header on the failure
+// holds off the next poll at least that long even while the breaker is closed.
+func (b *circuitBreaker) record(err error, now time.Time) {
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+
+		return
+	}
+
+	b.failures++
+
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+
+	if until := now.Add(retryAfter(err)); until.After(b.openUntil) {
+		b.openUntil = until
+	}
+}
@@ -0,0 +1,146 @@
+package configurator
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// CacheKeyVariable names the environment variable holding the base64-encoded AES-256
+	// key that encrypts the disk cache and vendored copies, used when WithCacheKey is not
+	// given.
+	CacheKeyVariable = "PROJECT_TOML_CACHE_KEY"
+	// PlaintextCacheVariable names the environment variable that, when true, allows the
+	// disk cache and vendored copies to be written unencrypted, used when
+	// WithPlaintextCache is not given.
+	PlaintextCacheVariable = "PROJECT_TOML_PLAINTEXT_CACHE"
+)
+
+var (
+	// ErrCacheKeyNotSet is returned when a document would be written to disk, in the disk
+	// cache or as a vendored copy, but no key is configured to encrypt it and plaintext
+	// copies are not allowed.
+	ErrCacheKeyNotSet = errors.New("no key to encrypt configuration at rest")
+	// ErrPlaintextCopy is returned when an unencrypted cache entry or vendored copy is
+	// read while plaintext copies are not allowed.
+	ErrPlaintextCopy = errors.New("unencrypted copy of configuration refused")
+)
+
+// WithCacheKey sets the AES-256 key that encrypts documents written to the disk cache
+// and vendored copies. Without it, CacheKeyVariable and then the secret key of
+// WithSecretKey are used.
+func WithCacheKey(key []byte) Option {
+	return func(o *options) {
+		o.cacheKey = key
+	}
+}
+
+// WithPlaintextCache allows documents to be written to the disk cache and as vendored
+// copies without encryption when no key is configured, for documents that hold no
+// secrets. Without it, such writes fail with ErrCacheKeyNotSet.
+func WithPlaintextCache(allow bool) Option {
+	return func(o *options) {
+		o.plaintextCache = allow
+	}
+}
+
+// defaultPlaintextCache reports whether PlaintextCacheVariable is set to true.
+func defaultPlaintextCache() bool {
+	allow, _ := strconv.ParseBool(os.Getenv(PlaintextCacheVariable))
+
+	return allow
+}
+
+// cacheCipher returns the cipher that encrypts documents at rest: the cache key, or the
+// secret key when no cache key is configured. It returns nil when neither is configured
+// and plaintext copies are allowed, and ErrCacheKeyNotSet when they are not.
+func (o *options) cacheCipher() (cipher.AEAD, error) {
+	key := o.cacheKey
+	if key == nil {
+		if encoded := os.Getenv(CacheKeyVariable); encoded != "" {
+			decoded, decodeErr := base64.StdEncoding.DecodeString(encoded)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", CacheKeyVariable, decodeErr)
+			}
+
+			key = decoded
+		}
+	}
+
+	if key != nil {
+		return newSecretCipher(key)
+	}
+
+	aead, secretErr := secretCipher(o)
+	switch {
+	case secretErr == nil:
+		return aead, nil
+	case !errors.Is(secretErr, ErrSecretKeyNotSet):
+		return nil, secretErr
+	case o.plaintextCache:
+		return nil, nil
+	default:
+		return nil, ErrCacheKeyNotSet
+	}
+}
+
+// atRestKeyDigest returns a digest of every key cacheCipher may use, so fetches that
+// read or write the disk cache with different keys are not coalesced. The keys
+// themselves are never kept.
+func (o *options) atRestKeyDigest() string {
+	digest := sha256.New()
+
+	for _, key := range [][]byte{
+		o.cacheKey, []byte(os.Getenv(CacheKeyVariable)), o.secretKey, []byte(os.Getenv(SecretKeyVariable)),
+	} {
+		digest.Write(binary.BigEndian.AppendUint64(nil, uint64(len(key))))
+		digest.Write(key)
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// sealCopy encrypts a document written to disk with aead, returning it unchanged when
+// aead is nil because plaintext copies are allowed.
+func sealCopy(aead cipher.AEAD, content []byte) (string, error) {
+	if aead == nil {
+		return "", nil
+	}
+
+	return sealValue(aead, content)
+}
+
+// openCopy decrypts a document read from disk that sealCopy encrypted. Unencrypted
+// copies, whose sealed form is empty, are refused unless plaintext copies are allowed.
+func (o *options) openCopy(sealed string, plaintext []byte) ([]byte, error) {
+	if sealed == "" {
+		if !o.plaintextCache {
+			return nil, ErrPlaintextCopy
+		}
+
+		return plaintext, nil
+	}
+
+	aead, keyErr := o.cacheCipher()
+	if keyErr != nil {
+		return nil, keyErr
+	}
+
+	if aead == nil {
+		return nil, ErrCacheKeyNotSet
+	}
+
+	opened, openErr := openSealed(aead, sealed)
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	return []byte(opened), nil
+}
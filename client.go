@@ -0,0 +1,254 @@
+package configurator
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxRedirects is the number of redirects followed before a fetch fails, matching
+	// net/http's default policy.
+	maxRedirects = 10
+	// maxCachedTransports bounds the transports kept for reuse, so a caller that builds a
+	// new client for every load does not accumulate copies of its transport.
+	maxCachedTransports = 16
+)
+
+var (
+	// ErrTooManyRedirects is returned when a fetch is redirected more often than allowed.
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrRedirectNotAllowed is returned when a redirect violates the redirect policy.
+	ErrRedirectNotAllowed = errors.New("redirect not allowed")
+	// ErrTransportNotSupported is returned when the transport of WithHTTPClient is not an
+	// *http.Transport, so internal addresses cannot be blocked on it or the client
+	// certificate and CA bundle cannot be applied to it.
+	ErrTransportNotSupported = errors.New("HTTP client transport cannot apply connection settings")
+)
+
+// RedirectPolicy restricts the redirects followed while fetching configuration.
+// MaxRedirects caps their number: zero keeps the default of 10 and a negative value
+// refuses every redirect. SameHost refuses redirects to a host other than the one
+// first requested, and NoDowngrade refuses redirects from HTTPS to plain HTTP.
+type RedirectPolicy struct {
+	MaxRedirects int
+	SameHost     bool
+	NoDowngrade  bool
+}
+
+// WithRedirectPolicy applies policy to the redirects followed while fetching
+// configuration, in addition to the host allowlist and HTTPS requirement.
+func WithRedirectPolicy(policy RedirectPolicy) Option {
+	return func(o *options) {
+		o.redirects = policy
+	}
+}
+
+// check applies the policy to a redirect to req after the requests in via. It reports
+// whether the number of redirects was checked, which is left to the client otherwise.
+func (p RedirectPolicy) check(req *http.Request, via []*http.Request) (bool, error) {
+	previous := via[len(via)-1]
+
+	if p.NoDowngrade && previous.URL.Scheme == httpsScheme && req.URL.Scheme != httpsScheme {
+		return false, fmt.Errorf("%w: downgrade from %s to %s", ErrRedirectNotAllowed, previous.URL.Redacted(), req.URL.Redacted())
+	}
+
+	if p.SameHost && !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		return false, fmt.Errorf("%w: host changed from %s to %s", ErrRedirectNotAllowed, via[0].URL.Host, req.URL.Host)
+	}
+
+	switch {
+	case p.MaxRedirects < 0:
+		return true, fmt.Errorf("%w: redirects are disabled", ErrTooManyRedirects)
+	case p.MaxRedirects > 0 && len(via) > p.MaxRedirects:
+		return true, fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, p.MaxRedirects)
+	default:
+		return p.MaxRedirects > 0, nil
+	}
+}
+
+// transportKey identifies the base transport and settings a cached transport was built
+// for.
+type transportKey struct {
+	base          *http.Transport
+	blockInternal bool
+	clientCert    string
+	clientKey     string
+	caBundle      string
+}
+
+// transportCache keeps the most recently used transports built for custom settings, so
+// connections are reused across fetches. Evicted transports have their idle connections
+// closed.
+type transportCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[any]*list.Element
+}
+
+// cachedTransport is a transport held by a transportCache under key.
+type cachedTransport struct {
+	key       any
+	transport *http.Transport
+}
+
+// transports caches the transports built for custom TLS settings and Unix domain
+// sockets.
+var transports = &transportCache{order: list.New(), entries: make(map[any]*list.Element)}
+
+// load returns the transport cached under key and marks it as recently used.
+func (c *transportCache) load(key any) (*http.Transport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	entry, _ := element.Value.(*cachedTransport)
+
+	return entry.transport, true
+}
+
+// loadOrStore returns the transport cached under key, or caches transport under key
+// and returns it, evicting the least recently used transport when the cache is full.
+func (c *transportCache) loadOrStore(key any, transport *http.Transport) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[key]; found {
+		c.order.MoveToFront(element)
+		entry, _ := element.Value.(*cachedTransport)
+
+		return entry.transport
+	}
+
+	c.entries[key] = c.order.PushFront(&cachedTransport{key: key, transport: transport})
+
+	if c.order.Len() > maxCachedTransports {
+		oldest, _ := c.order.Remove(c.order.Back()).(*cachedTransport)
+		delete(c.entries, oldest.key)
+		oldest.transport.CloseIdleConnections()
+	}
+
+	return transport
+}
+
+// WithHTTPClient makes configuration and Vault requests with client instead of
+// http.DefaultClient, e.g. to add instrumentation or a custom transport. When
+// WithClientCertificate, WithCABundle, or WithBlockInternalAddresses apply, configuration
+// is fetched with a copy of its *http.Transport that adds them, of which only the most
+// recently used are kept for reuse, and fetches fail with
+// ErrTransportNotSupported when its transport is of another type. The host allowlist and
+// HTTPS requirement cover redirects, followed by the client's own redirect policy.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// httpClient returns the client used to fetch remote configuration, which applies the
+// URL checks of settings to every redirect, blocks internal addresses when asked to,
+// and presents the configured client certificate.
+func httpClient(settings *options) (*http.Client, error) {
+	transport, transportErr := httpTransport(settings)
+	if transportErr != nil {
+		return nil, transportErr
+	}
+
+	if settings.httpClient != nil {
+		client := *settings.httpClient
+		client.Transport = transport
+		client.CheckRedirect = checkRedirect(settings, settings.httpClient.CheckRedirect)
+
+		return &client, nil
+	}
+
+	client := *http.DefaultClient
+	client.Transport = transport
+	client.CheckRedirect = checkRedirect(settings, nil)
+
+	return &client, nil
+}
+
+// checkRedirect returns a redirect policy that applies the URL checks and redirect
+// policy of settings, strips credentials from redirects to hosts that may not receive
+// them, and then applies next, or the default limit of maxRedirects when next is
+// nil and the policy sets no limit.
+func checkRedirect(settings *options, next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		checkErr := checkURL(req.URL, settings)
+		if checkErr != nil {
+			return checkErr
+		}
+
+		limited, policyErr := settings.redirects.check(req, via)
+		if policyErr != nil {
+			return policyErr
+		}
+
+		settings.stripCredentials(req)
+
+		if next != nil {
+			return next(req, via)
+		}
+
+		if !limited && len(via) >= maxRedirects {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, maxRedirects)
+		}
+
+		return nil
+	}
+}
+
+// httpTransport returns the round tripper for settings: the transport of the configured
+// client, or http.DefaultTransport, copied and cached with the internal address block
+// and custom TLS applied when those are configured.
+func httpTransport(settings *options) (http.RoundTripper, error) {
+	base := http.DefaultTransport
+	if settings.httpClient != nil && settings.httpClient.Transport != nil {
+		base = settings.httpClient.Transport
+	}
+
+	if !settings.blockInternal && !settings.usesCustomTLS() {
+		return base, nil
+	}
+
+	baseTransport, supported := base.(*http.Transport)
+	if !supported {
+		return nil, fmt.Errorf("%w: %T", ErrTransportNotSupported, base)
+	}
+
+	key := transportKey{
+		base:          baseTransport,
+		blockInternal: settings.blockInternal,
+		clientCert:    settings.clientCert,
+		clientKey:     settings.clientKey,
+		caBundle:      settings.caBundle,
+	}
+
+	if cached, found := transports.load(key); found {
+		return cached, nil
+	}
+
+	transport := baseTransport.Clone()
+	if settings.blockInternal {
+		blockInternalDials(transport)
+	}
+
+	if settings.usesCustomTLS() {
+		config, configErr := settings.tlsConfig(transport.TLSClientConfig)
+		if configErr != nil {
+			return nil, configErr
+		}
+
+		transport.TLSClientConfig = config
+	}
+
+	return transports.loadOrStore(key, transport), nil
+}
@@ -0,0 +1,175 @@
+package configurator
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redirectServer starts a server that redirects /hop/<n> to /hop/<n-1> and /hop/0 to
+// target, so a fetch of /hop/<n> follows n+1 redirects.
+func redirectServer(t *testing.T, target string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hop int
+
+		_, scanErr := fmt.Sscanf(r.URL.Path, "/hop/%d", &hop)
+		if scanErr != nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		next := target
+		if hop > 0 {
+			next = fmt.Sprintf("/hop/%d", hop-1)
+		}
+
+		http.Redirect(w, r, next, http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRedirectPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	request := func(url string) *http.Request {
+		req, requestErr := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, requestErr)
+
+		return req
+	}
+
+	via := []*http.Request{request("https://cfg.example/project.toml")}
+
+	_, disabledErr := RedirectPolicy{MaxRedirects: -1}.check(request("https://cfg.example/moved.toml"), via)
+	require.ErrorIs(t, disabledErr, ErrTooManyRedirects)
+
+	_, hostErr := RedirectPolicy{SameHost: true}.check(request("https://other.example/project.toml"), via)
+	require.ErrorIs(t, hostErr, ErrRedirectNotAllowed)
+
+	_, downgradeErr := RedirectPolicy{NoDowngrade: true}.check(request("http://cfg.example/project.toml"), via)
+	require.ErrorIs(t, downgradeErr, ErrRedirectNotAllowed)
+
+	limited, allowedErr := RedirectPolicy{SameHost: true, NoDowngrade: true}.check(request("https://CFG.example/moved.toml"), via)
+	require.NoError(t, allowedErr)
+	assert.False(t, limited)
+
+	limited, limitErr := RedirectPolicy{MaxRedirects: 1}.check(request("https://cfg.example/b.toml"), append(via, request("https://cfg.example/a.toml")))
+	require.ErrorIs(t, limitErr, ErrTooManyRedirects)
+	assert.True(t, limited)
+}
+
+func TestLoadFollowsRedirects(t *testing.T) {
+	documents := serveDocuments(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	server := redirectServer(t, documents.URL+"/project.toml")
+
+	tests := map[string]struct {
+		path    string
+		opts    []Option
+		wantErr error
+	}{
+		"within the default limit": {path: "/hop/8"},
+		"beyond the default limit": {path: "/hop/9", wantErr: ErrTooManyRedirects},
+		"within a custom limit": {
+			path: "/hop/1",
+			opts: []Option{WithRedirectPolicy(RedirectPolicy{MaxRedirects: 2})},
+		},
+		"beyond a custom limit": {
+			path:    "/hop/2",
+			opts:    []Option{WithRedirectPolicy(RedirectPolicy{MaxRedirects: 2})},
+			wantErr: ErrTooManyRedirects,
+		},
+		"disabled": {
+			path:    "/hop/0",
+			opts:    []Option{WithRedirectPolicy(RedirectPolicy{MaxRedirects: -1})},
+			wantErr: ErrTooManyRedirects,
+		},
+		"to another host": {
+			path:    "/hop/0",
+			opts:    []Option{WithRedirectPolicy(RedirectPolicy{SameHost: true})},
+			wantErr: ErrRedirectNotAllowed,
+		},
+		"to an allowed host": {
+			path: "/hop/0",
+			opts: []Option{WithAllowedHosts("127.0.0.1")},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROJECT_TOML", server.URL+test.path)
+
+			var config testConfig
+
+			loadErr := Load(&config, newTestLogger(t), test.opts...)
+			if test.wantErr != nil {
+				require.ErrorIs(t, loadErr, test.wantErr)
+
+				return
+			}
+
+			require.NoError(t, loadErr)
+			assert.Equal(t, "tts", config.Service.Name)
+		})
+	}
+}
+
+func TestLoadChecksRedirectTargetsAgainstTheAllowlist(t *testing.T) {
+	documents := serveDocuments(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	target := strings.Replace(documents.URL, "127.0.0.1", "localhost", 1) + "/project.toml"
+	server := redirectServer(t, target)
+	t.Setenv("PROJECT_TOML", server.URL+"/hop/0")
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithAllowedHosts("127.0.0.1")), ErrHostNotAllowed)
+}
+
+func TestTransportCacheIsBounded(t *testing.T) {
+	t.Parallel()
+
+	cache := &transportCache{order: list.New(), entries: make(map[any]*list.Element)}
+	first := &http.Transport{}
+
+	assert.Same(t, first, cache.loadOrStore(0, first))
+	assert.Same(t, first, cache.loadOrStore(0, &http.Transport{}), "the cached transport wins")
+
+	for key := 1; key < 2*maxCachedTransports; key++ {
+		cache.loadOrStore(key, &http.Transport{})
+	}
+
+	assert.Equal(t, maxCachedTransports, cache.order.Len())
+	assert.Len(t, cache.entries, maxCachedTransports)
+
+	_, found := cache.load(0)
+	assert.False(t, found, "the least recently used transport is evicted")
+
+	_, found = cache.load(2*maxCachedTransports - 1)
+	assert.True(t, found)
+}
+
+func TestHTTPTransportReusesCopyPerClient(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	settings := newOptions([]Option{WithHTTPClient(client), WithBlockInternalAddresses(true)})
+
+	first, firstErr := httpTransport(settings)
+	require.NoError(t, firstErr)
+
+	second, secondErr := httpTransport(settings)
+	require.NoError(t, secondErr)
+
+	assert.Same(t, first, second)
+	assert.NotSame(t, client.Transport, first)
+}
@@ -0,0 +1,47 @@
+package configurator
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding lists the content encodings requested for configuration documents.
+const acceptEncoding = "zstd, gzip"
+
+// ErrUnsupportedEncoding is returned when a response uses a content encoding that cannot
+// be decoded.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// decodedBody returns a reader for the body of resp with its Content-Encoding removed.
+// Responses that net/http already decompressed carry no Content-Encoding and are read
+// as is.
+func decodedBody(resp *http.Response) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		reader, gzipErr := gzip.NewReader(resp.Body)
+		if gzipErr != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", gzipErr)
+		}
+
+		return reader, nil
+	case "zstd":
+		decoder, zstdErr := zstd.NewReader(resp.Body, zstd.WithDecoderConcurrency(1))
+		if zstdErr != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", zstdErr)
+		}
+
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+}
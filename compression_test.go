@@ -0,0 +1,124 @@
+package configurator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressedDocument is the document the compression tests serve.
+const compressedDocument = "[service]\nname = \"tts\"\nport = 8080\n"
+
+// gzipped returns content compressed with gzip.
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	_, writeErr := writer.Write([]byte(content))
+	require.NoError(t, writeErr)
+	require.NoError(t, writer.Close())
+
+	return compressed.Bytes()
+}
+
+// zstdCompressed returns content compressed with zstd.
+func zstdCompressed(t *testing.T, content string) []byte {
+	t.Helper()
+
+	encoder, encoderErr := zstd.NewWriter(nil)
+	require.NoError(t, encoderErr)
+
+	defer func() {
+		require.NoError(t, encoder.Close())
+	}()
+
+	return encoder.EncodeAll([]byte(content), nil)
+}
+
+// serveEncoded starts a server answering every request with body in encoding, and
+// points PROJECT_TOML at it. It returns the Accept-Encoding of the last request.
+func serveEncoded(t *testing.T, encoding string, body []byte) func() string {
+	t.Helper()
+
+	var accepted atomic.Value
+
+	accepted.Store("")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted.Store(r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", encoding)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	return func() string {
+		value, _ := accepted.Load().(string)
+
+		return value
+	}
+}
+
+func TestLoadDecodesCompressedDocuments(t *testing.T) {
+	for encoding, body := range map[string][]byte{
+		"gzip":     gzipped(t, compressedDocument),
+		"x-gzip":   gzipped(t, compressedDocument),
+		"zstd":     zstdCompressed(t, compressedDocument),
+		"identity": []byte(compressedDocument),
+	} {
+		t.Run(encoding, func(t *testing.T) {
+			accepted := serveEncoded(t, encoding, body)
+
+			var config testConfig
+
+			require.NoError(t, Load(&config, newTestLogger(t)))
+			assert.Equal(t, 8080, config.Service.Port)
+			assert.Equal(t, acceptEncoding, accepted())
+		})
+	}
+}
+
+func TestLoadCapsDecompressedSize(t *testing.T) {
+	large := compressedDocument + "padding = \"" + strings.Repeat("a", 64<<10) + "\"\n"
+
+	for encoding, body := range map[string][]byte{
+		"gzip": gzipped(t, large),
+		"zstd": zstdCompressed(t, large),
+	} {
+		t.Run(encoding, func(t *testing.T) {
+			require.Less(t, len(body), 4<<10, "the compressed body is under the cap")
+			serveEncoded(t, encoding, body)
+
+			var config testConfig
+
+			require.ErrorIs(t, Load(&config, newTestLogger(t), WithMaxBodySize(4<<10)), ErrResponseTooLarge)
+			require.NoError(t, Load(&config, newTestLogger(t), WithMaxBodySize(128<<10)))
+		})
+	}
+}
+
+func TestLoadRejectsUnsupportedEncodings(t *testing.T) {
+	serveEncoded(t, "br", []byte(compressedDocument))
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrUnsupportedEncoding)
+}
+
+func TestLoadRejectsCorruptCompressedDocuments(t *testing.T) {
+	serveEncoded(t, "gzip", []byte("not gzip"))
+
+	var config testConfig
+
+	require.ErrorContains(t, Load(&config, newTestLogger(t)), "decompress")
+}
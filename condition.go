@@ -0,0 +1,350 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// conditionKey is the key holding the condition under which a table applies. The
+// leading underscore keeps it apart from ordinary settings, so a table may still have a
+// when key of its own.
+const conditionKey = "_when"
+
+// ErrInvalidCondition is returned when a _when condition cannot be parsed or refers to an
+// unknown variable.
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// conditionVariables returns the values conditions are evaluated against: os and arch
+// of the running binary, and the selected env and profile, which are empty when none
+// is selected.
+func conditionVariables(settings *options) map[string]string {
+	return map[string]string{
+		"os":      runtime.GOOS,
+		"arch":    runtime.GOARCH,
+		"env":     selectedEnvironment(settings),
+		"profile": selectedProfile(settings),
+	}
+}
+
+// applyConditions evaluates the _when conditions of every layer, dropping the tables
+// whose condition is false and removing the _when key from the others. A condition on a
+// layer's top level applies to the whole layer.
+func applyConditions(state *assembly) error {
+	pruner := &conditionPruner{variables: conditionVariables(state.settings)}
+
+	for layer, table := range state.layers {
+		pruned, keep, pruneErr := pruner.table(string(layer), table)
+		if pruneErr != nil {
+			return pruneErr
+		}
+
+		if keep {
+			state.setLayer(layer, pruned)
+		} else {
+			delete(state.layers, layer)
+		}
+	}
+
+	if pruner.found {
+		state.markModified()
+	}
+
+	return nil
+}
+
+// conditionPruner removes the tables whose _when condition is false. found records
+// whether any condition was seen.
+type conditionPruner struct {
+	variables map[string]string
+	found     bool
+}
+
+// table returns a copy of table without its _when key and without nested tables whose
+// condition is false. The boolean result is false when table's own condition is false.
+func (p *conditionPruner) table(path string, table map[string]any) (map[string]any, bool, error) {
+	if expression, conditional := table[conditionKey]; conditional {
+		p.found = true
+
+		text, isString := expression.(string)
+		if !isString {
+			return nil, false, fmt.Errorf("%w: %s: %s must be a string", ErrInvalidCondition, path, conditionKey)
+		}
+
+		holds, evalErr := evaluateCondition(text, p.variables)
+		if evalErr != nil {
+			return nil, false, fmt.Errorf("%s: %w", path, evalErr)
+		}
+
+		if !holds {
+			return nil, false, nil
+		}
+	}
+
+	pruned := make(map[string]any, len(table))
+
+	for key, value := range table {
+		if key == conditionKey {
+			continue
+		}
+
+		kept, keep, valueErr := p.value(joinKey(path, key), value)
+		if valueErr != nil {
+			return nil, false, valueErr
+		}
+
+		if keep {
+			pruned[key] = kept
+		}
+	}
+
+	return pruned, true, nil
+}
+
+// value prunes the tables within value, which may be a table or an array of tables.
+func (p *conditionPruner) value(path string, value any) (any, bool, error) {
+	switch typed := value.(type) {
+	case map[string]any:
+		return p.table(path, typed)
+	case []any:
+		elements := make([]any, 0, len(typed))
+
+		for index, element := range typed {
+			kept, keep, elementErr := p.value(fmt.Sprintf("%s[%d]", path, index), element)
+			if elementErr != nil {
+				return nil, false, elementErr
+			}
+
+			if keep {
+				elements = append(elements, kept)
+			}
+		}
+
+		return elements, true, nil
+	default:
+		return value, true, nil
+	}
+}
+
+// evaluateCondition evaluates expression against variables. Expressions compare
+// variables and quoted strings with == and !=, and combine comparisons with &&, ||, !,
+// and parentheses, e.g. os == "linux" && env != 'dev'.
+func evaluateCondition(expression string, variables map[string]string) (bool, error) {
+	tokens, tokenizeErr := tokenizeCondition(expression)
+	if tokenizeErr != nil {
+		return false, tokenizeErr
+	}
+
+	parser := &conditionParser{tokens: tokens, variables: variables}
+
+	result, parseErr := parser.or()
+	if parseErr != nil {
+		return false, parseErr
+	}
+
+	if parser.position < len(parser.tokens) {
+		return false, fmt.Errorf("%w: unexpected %q", ErrInvalidCondition, parser.tokens[parser.position].text)
+	}
+
+	return result, nil
+}
+
+// conditionTokenKind classifies the tokens of a condition.
+type conditionTokenKind int
+
+const (
+	tokenOperator conditionTokenKind = iota
+	tokenIdentifier
+	tokenString
+)
+
+// conditionToken is a single token of a condition.
+type conditionToken struct {
+	kind conditionTokenKind
+	text string
+}
+
+// conditionOperators lists the operators, two-character ones first so they win over
+// their one-character prefixes.
+var conditionOperators = []string{"&&", "||", "==", "!=", "!", "(", ")"}
+
+// tokenizeCondition splits expression into operators, identifiers, and string literals.
+func tokenizeCondition(expression string) ([]conditionToken, error) {
+	var tokens []conditionToken
+
+	rest := expression
+
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+
+		token, length, tokenErr := nextConditionToken(rest)
+		if tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		tokens = append(tokens, token)
+		rest = rest[length:]
+	}
+}
+
+// nextConditionToken reads the token at the start of rest and returns it with the
+// number of bytes it spans.
+func nextConditionToken(rest string) (conditionToken, int, error) {
+	for _, operator := range conditionOperators {
+		if strings.HasPrefix(rest, operator) {
+			return conditionToken{kind: tokenOperator, text: operator}, len(operator), nil
+		}
+	}
+
+	if quote := rest[0]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 {
+			return conditionToken{}, 0, fmt.Errorf("%w: unterminated string %s", ErrInvalidCondition, rest)
+		}
+
+		return conditionToken{kind: tokenString, text: rest[1 : end+1]}, end + 2, nil
+	}
+
+	length := strings.IndexFunc(rest, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if length < 0 {
+		length = len(rest)
+	}
+
+	if length == 0 {
+		return conditionToken{}, 0, fmt.Errorf("%w: unexpected character %q", ErrInvalidCondition, rest[0])
+	}
+
+	return conditionToken{kind: tokenIdentifier, text: rest[:length]}, length, nil
+}
+
+// conditionParser evaluates a tokenized condition by recursive descent, from the
+// loosest-binding operator down.
+type conditionParser struct {
+	tokens    []conditionToken
+	position  int
+	variables map[string]string
+}
+
+// or evaluates a sequence of && groups joined by ||.
+func (p *conditionParser) or() (bool, error) {
+	result, leftErr := p.and()
+	if leftErr != nil {
+		return false, leftErr
+	}
+
+	for p.accept("||") {
+		right, rightErr := p.and()
+		if rightErr != nil {
+			return false, rightErr
+		}
+
+		result = result || right
+	}
+
+	return result, nil
+}
+
+// and evaluates a sequence of unary terms joined by &&.
+func (p *conditionParser) and() (bool, error) {
+	result, leftErr := p.unary()
+	if leftErr != nil {
+		return false, leftErr
+	}
+
+	for p.accept("&&") {
+		right, rightErr := p.unary()
+		if rightErr != nil {
+			return false, rightErr
+		}
+
+		result = result && right
+	}
+
+	return result, nil
+}
+
+// unary evaluates a negation, a parenthesized expression, or a comparison.
+func (p *conditionParser) unary() (bool, error) {
+	if p.accept("!") {
+		result, operandErr := p.unary()
+
+		return !result, operandErr
+	}
+
+	if p.accept("(") {
+		result, innerErr := p.or()
+		if innerErr != nil {
+			return false, innerErr
+		}
+
+		if !p.accept(")") {
+			return false, fmt.Errorf("%w: missing )", ErrInvalidCondition)
+		}
+
+		return result, nil
+	}
+
+	return p.comparison()
+}
+
+// comparison evaluates operand == operand or operand != operand.
+func (p *conditionParser) comparison() (bool, error) {
+	left, leftErr := p.operand()
+	if leftErr != nil {
+		return false, leftErr
+	}
+
+	equal := p.accept("==")
+	if !equal && !p.accept("!=") {
+		return false, fmt.Errorf("%w: expected == or != after %q", ErrInvalidCondition, left)
+	}
+
+	right, rightErr := p.operand()
+	if rightErr != nil {
+		return false, rightErr
+	}
+
+	return (left == right) == equal, nil
+}
+
+// operand returns the value of a variable or string literal.
+func (p *conditionParser) operand() (string, error) {
+	if p.position >= len(p.tokens) {
+		return "", fmt.Errorf("%w: unexpected end of condition", ErrInvalidCondition)
+	}
+
+	token := p.tokens[p.position]
+	p.position++
+
+	switch token.kind {
+	case tokenString:
+		return token.text, nil
+	case tokenIdentifier:
+		value, known := p.variables[token.text]
+		if !known {
+			return "", fmt.Errorf("%w: unknown variable %s", ErrInvalidCondition, token.text)
+		}
+
+		return value, nil
+	default:
+		return "", fmt.Errorf("%w: unexpected %q", ErrInvalidCondition, token.text)
+	}
+}
+
+// accept consumes the next token when it is the given operator.
+func (p *conditionParser) accept(operator string) bool {
+	if p.position < len(p.tokens) && p.tokens[p.position].kind == tokenOperator && p.tokens[p.position].text == operator {
+		p.position++
+
+		return true
+	}
+
+	return false
+}
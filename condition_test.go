@@ -0,0 +1,127 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	t.Parallel()
+
+	variables := map[string]string{"os": "linux", "arch": "amd64", "env": "prod", "profile": ""}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`os == "linux"`, true},
+		{`os != 'linux'`, false},
+		{`"linux" == os`, true},
+		{`profile == ""`, true},
+		{`os == "linux" && env == "dev"`, false},
+		{`os == "darwin" || env == "prod"`, true},
+		{`os == "darwin" || env == "prod" && arch == "arm64"`, false},
+		{`(os == "darwin" || env == "prod") && arch == "amd64"`, true},
+		{`!(os == "linux")`, false},
+		{`!os == "darwin"`, true},
+		{`!!(env=="prod")`, true},
+		{`  os=="linux"&&arch!="arm64"  `, true},
+		{`env == "a b" || env == "it's"`, false},
+	}
+
+	for _, test := range tests {
+		got, evalErr := evaluateCondition(test.expression, variables)
+		require.NoError(t, evalErr, test.expression)
+		assert.Equal(t, test.want, got, test.expression)
+	}
+}
+
+func TestEvaluateConditionRejectsMalformedExpressions(t *testing.T) {
+	t.Parallel()
+
+	variables := map[string]string{"os": "linux"}
+
+	for _, expression := range []string{
+		``,
+		`os`,
+		`os ==`,
+		`os = "linux"`,
+		`os == "linux`,
+		`(os == "linux"`,
+		`os == "linux")`,
+		`os == "linux" "extra"`,
+		`cpu == "x86"`,
+		`os == "linux" &&`,
+		`os == "linux" & os == "linux"`,
+		`== "linux"`,
+		`os == $HOME`,
+	} {
+		_, evalErr := evaluateCondition(expression, variables)
+		require.ErrorIs(t, evalErr, ErrInvalidCondition, expression)
+	}
+}
+
+func TestLoadDropsTablesWhoseConditionIsFalse(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[service]
+name = "tts"
+
+[here]
+_when = 'os == "`+runtime.GOOS+`" && env == "prod"'
+enabled = true
+
+[elsewhere]
+_when = 'os == "plan9"'
+enabled = true
+
+[schedule]
+when = "daily"
+
+[[workers]]
+_when = 'env == "dev"'
+name = "debug"
+
+[[workers]]
+name = "main"
+`), 0o600))
+	t.Setenv("PROJECT_TOML", path)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithEnvironment("prod")))
+	assert.Equal(t, map[string]any{"enabled": true}, config["here"])
+	assert.NotContains(t, config, "elsewhere")
+	assert.Equal(t, map[string]any{"when": "daily"}, config["schedule"])
+	assert.Equal(t, []any{map[string]any{"name": "main"}}, config["workers"])
+}
+
+func TestLoadAppliesTopLevelConditionToTheWholeLayer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.toml"), []byte("[service]\nport = 80\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.prod.toml"),
+		[]byte("_when = 'os == \"plan9\"'\n[service]\nport = 443\n"), 0o600))
+	t.Setenv("PROJECT_TOML", filepath.Join(dir, "project.toml"))
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithEnvironment("prod")))
+	assert.Equal(t, 80, config.Service.Port)
+}
+
+func TestLoadRejectsNonStringCondition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[service]\n_when = true\n"), 0o600))
+	t.Setenv("PROJECT_TOML", path)
+
+	var config map[string]any
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrInvalidCondition)
+}
@@ -0,0 +1,70 @@
+package configurator
+
+import (
+	"net/http"
+	"sync"
+)
+
+// cachedResponse holds the validators, body, and document format of a successful
+// response.
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	body         []byte
+	format       string
+}
+
+// fetchCache remembers the last successful response per URL so repeated fetches can be
+// conditional: a 304 answer reuses the cached body. A nil *fetchCache disables
+// conditional requests. It is safe for concurrent use.
+type fetchCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// newFetchCache returns an empty fetchCache.
+func newFetchCache() *fetchCache {
+	return &fetchCache{entries: make(map[string]cachedResponse)}
+}
+
+// apply adds conditional request headers for the cached response to req.
+func (r cachedResponse) apply(req *http.Request) {
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+
+	if r.lastModified != "" {
+		req.Header.Set("If-Modified-Since", r.lastModified)
+	}
+}
+
+// lookup returns the response cached for url.
+func (c *fetchCache) lookup(url string) (cachedResponse, bool) {
+	if c == nil {
+		return cachedResponse{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[url]
+
+	return entry, found
+}
+
+// store records the validators and body of a successful response for url.
+func (c *fetchCache) store(url string, resp *http.Response, body []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[url] = cachedResponse{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		body:         body,
+		format:       documentFormat(resp.Header.Get("Content-Type")),
+	}
+}
@@ -14,8 +14,15 @@ import (
 	"github.com/pelletier/go-toml/v2"
 )
 
-// DefaultURLTimeout defines the default timeout for fetching the configuration URL.
-const DefaultURLTimeout = 10 * time.Second
+const (
+	// DefaultURLTimeout defines the default timeout for fetching the configuration URL.
+	DefaultURLTimeout = 10 * time.Second
+	// URLTimeoutVariable names the environment variable holding the fetch timeout as a
+	// Go duration such as 30s, used when WithURLTimeout is not given.
+	URLTimeoutVariable = "PROJECT_TOML_TIMEOUT"
+	// DefaultMaxBodySize caps the size of a fetched configuration document.
+	DefaultMaxBodySize = 4 << 20
+)
 
 // ErrUnexpectedHTTPStatus is returned when the HTTP request to fetch the TOML file does not return a 200 OK status.
 var ErrUnexpectedHTTPStatus = errors.New("unexpected HTTP status")
@@ -23,41 +30,269 @@ var ErrUnexpectedHTTPStatus = errors.New("unexpected HTTP status")
 // ErrProjectTomlNotSet is returned when the PROJECT_TOML environment variable is not set.
 var ErrProjectTomlNotSet = errors.New("PROJECT_TOML environment variable not set")
 
+// ErrResponseTooLarge is returned when a fetched document exceeds the maximum body size.
+var ErrResponseTooLarge = errors.New("configuration response too large")
+
+// WithMaxBodySize caps the size of fetched configuration documents, DefaultMaxBodySize
+// by default, so a URL pointing at a huge file cannot exhaust memory.
+func WithMaxBodySize(limit int64) Option {
+	return func(o *options) {
+		o.maxBodySize = limit
+	}
+}
+
+// WithURLTimeout bounds each request made while loading the configuration, including
+// secret lookups, instead of DefaultURLTimeout.
+func WithURLTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.urlTimeout = timeout
+	}
+}
+
+// WithContext bounds loading with ctx: cancelling it aborts requests in flight, secret
+// lookups, and waits between retries. Watch and NewStore load under their own context.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.loadContext = ctx
+	}
+}
+
+// defaultURLTimeout returns the timeout from URLTimeoutVariable, or DefaultURLTimeout
+// when it is unset or not a positive duration.
+func defaultURLTimeout() time.Duration {
+	timeout, parseErr := time.ParseDuration(os.Getenv(URLTimeoutVariable))
+	if parseErr != nil || timeout <= 0 {
+		return DefaultURLTimeout
+	}
+
+	return timeout
+}
+
 // Load fetches application configuration from a remote URL, specified by the PROJECT_TOML
-// environment variable, and unmarshals it into a type-safe Go struct.
+// environment variable, and unmarshals it into a type-safe Go struct. PROJECT_TOML may
+// also name a local file, either as a plain path or a file:// URL.
 // It acts as a centralized configuration client for other services within the Book Expert project.
-func Load(target any, logger *logger.Logger) error {
+// Options may be passed to customize fetching and verification.
+func Load(target any, logger *logger.Logger, opts ...Option) error {
+	settings := newOptions(opts)
+
+	effective, contentErr := loadContent(settings, nil, logger)
+	if contentErr != nil {
+		return contentErr
+	}
+
+	auditErr := auditPermissions(effective.files, target, []string{""}, settings, logger)
+	if auditErr != nil {
+		return auditErr
+	}
+
+	unmarshalErr := unmarshalTOML(effective.content, target)
+	if unmarshalErr != nil {
+		return fmt.Errorf("failed to unmarshal TOML: %w", unmarshalErr)
+	}
+
+	return revealSecrets(target, settings)
+}
+
+// loadContent reads the base configuration document, verifies it as served against the
+// lock file and its signatures when those are configured, converts it to TOML, and
+// merges its overlays into the effective document. Every included and overlaid document
+// is verified the same way, and the lock file must list exactly the documents merged.
+// A non-nil cache enables conditional HTTP requests. With a disk cache, the load fails
+// up front when documents could not be encrypted at rest.
+func loadContent(settings *options, cache *fetchCache, logger *logger.Logger) (*document, error) {
+	if settings.cacheDir != "" {
+		_, keyErr := settings.cacheCipher()
+		if keyErr != nil {
+			return nil, fmt.Errorf("disk cache %s: %w", settings.cacheDir, keyErr)
+		}
+	}
+
+	tomlContent, source, format, readErr := readContent(settings, cache, logger)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if settings.lockFile != "" {
+		verifyErr := verifyLock(settings.lockFile, tomlContent)
+		if verifyErr != nil {
+			return nil, verifyErr
+		}
+	}
+
+	signatureErr := verifySignatures(settings, source, tomlContent, cache, logger)
+	if signatureErr != nil {
+		return nil, signatureErr
+	}
+
+	converted, convertErr := convertDocument(tomlContent, format)
+	if convertErr != nil {
+		return nil, fmt.Errorf("%s: %w", source, convertErr)
+	}
+
+	effective, assembleErr := assemble(converted, source, settings, cache, logger)
+	if assembleErr != nil {
+		return nil, assembleErr
+	}
+
+	if settings.lockFile != "" {
+		verifyErr := verifyLockedLayers(settings.lockFile, effective.layers)
+		if verifyErr != nil {
+			return nil, verifyErr
+		}
+	}
+
+	return effective, nil
+}
+
+// readContent returns the raw base configuration document, the source it was read from,
+// and its format, preferring a vendored copy when one is configured and present, and
+// fetching PROJECT_TOML otherwise.
+func readContent(settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, string, error) {
+	if settings.vendoredCopy != "" {
+		content, format, found, vendorErr := readVendoredCopy(settings, settings.vendoredCopy)
+		if vendorErr != nil {
+			return nil, "", "", vendorErr
+		}
+
+		if found {
+			return content, settings.vendoredCopy, format, nil
+		}
+	}
+
 	projectTOMLURL := os.Getenv("PROJECT_TOML")
 	if projectTOMLURL == "" {
-		return ErrProjectTomlNotSet
+		return nil, "", "", ErrProjectTomlNotSet
 	}
 
-	tomlContent, fetchErr := fetchURL(projectTOMLURL, logger)
+	tomlContent, format, fetchErr := readSource(projectTOMLURL, settings, cache, logger)
 	if fetchErr != nil {
-		return fmt.Errorf("failed to fetch TOML from %s: %w", projectTOMLURL, fetchErr)
+		return nil, "", "", fmt.Errorf("failed to fetch TOML from %s: %w", projectTOMLURL, fetchErr)
 	}
 
-	unmarshalErr := unmarshalTOML(tomlContent, target)
-	if unmarshalErr != nil {
-		return fmt.Errorf("failed to unmarshal TOML: %w", unmarshalErr)
+	source, _ := splitPin(projectTOMLURL)
+
+	return tomlContent, source, format, nil
+}
+
+// fetchURL handles the HTTP request to fetch the TOML file from the specified URL, and
+// returns its body with the document format its Content-Type named,
+// sharing the result of an identical request already in progress, retrying transient
+// failures as the retry policy of settings allows, and falling back to the
+// last-known-good copy when enabled. In offline mode the cached copy is returned
+// without a request.
+// When cache is non-nil, or a cache directory is configured, the request is conditional
+// on the response cached for url, whose body is reused when the server answers 304 Not
+// Modified.
+func fetchURL(url string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, error) {
+	if settings.offline {
+		return settings.offlineCopy(url, logger)
 	}
 
-	return nil
+	body, format, transient, fetchErr := fetchShared(url, settings, cache, logger)
+	if fetchErr != nil && transient && settings.staleFallback {
+		return settings.staleCopy(url, fetchErr, logger)
+	}
+
+	return body, format, fetchErr
 }
 
-// fetchURL handles the HTTP request to fetch the TOML file from the specified URL.
-func fetchURL(url string, logger *logger.Logger) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultURLTimeout)
+// fetchWithRetries fetches url, retrying transient failures. A Retry-After longer than
+// the policy's MaxDelay ends the retries early, and the wait between attempts ends when
+// the load's context is cancelled. The boolean result reports whether the final failure
+// was transient.
+func fetchWithRetries(url string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, bool, error) {
+	for attempt := 1; ; attempt++ {
+		body, format, transient, fetchErr := fetchOnce(url, settings, cache, logger)
+		if fetchErr == nil || !transient || attempt >= settings.retry.attempts() {
+			return body, format, transient, fetchErr
+		}
+
+		requested := retryAfter(fetchErr)
+		if requested > settings.retry.maxDelay() {
+			return body, format, transient, fetchErr
+		}
+
+		delay := max(settings.retry.delay(attempt), requested)
+		logger.Warn("fetching %s failed, retrying in %s: %v", url, delay, fetchErr)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-settings.loadContext.Done():
+			timer.Stop()
+
+			return nil, "", false, fmt.Errorf("failed to fetch %s: %w", url, settings.loadContext.Err())
+		}
+	}
+}
+
+// fetchOnce makes a single request for url and returns the body as served with its
+// document format. The boolean result reports whether a failure is transient and worth
+// retrying, which includes a 401 response that evicted the cached OAuth2 token. The
+// OAuth2 token is requested over the network even when url names a Unix domain socket.
+func fetchOnce(url string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, bool, error) {
+	ctx, cancel := context.WithTimeout(settings.loadContext, settings.urlTimeout)
 	defer cancel()
 
-	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	requestURL, socket, isSocket, socketErr := splitSocketURL(url)
+	if socketErr != nil {
+		return nil, "", false, socketErr
+	}
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if newRequestErr != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", newRequestErr)
+		return nil, "", false, fmt.Errorf("failed to create HTTP request: %w", newRequestErr)
+	}
+
+	checkErr := checkSource(req.URL, isSocket, settings)
+	if checkErr != nil {
+		return nil, "", false, checkErr
+	}
+
+	client, clientErr := httpClient(settings)
+	if clientErr != nil {
+		return nil, "", false, clientErr
 	}
 
-	resp, doRequestErr := http.DefaultClient.Do(req)
+	fetchClient := client
+	if isSocket {
+		socketClient := *client
+		socketClient.Transport = socketTransport(socket)
+		fetchClient = &socketClient
+	}
+
+	credentials := settings.sendsCredentials(url)
+	settings.authorize(req, credentials)
+
+	if credentials {
+		oauth2Err := settings.applyOAuth2(ctx, req, client)
+		if oauth2Err != nil {
+			return nil, "", false, oauth2Err
+		}
+
+		signErr := settings.signSigV4(req)
+		if signErr != nil {
+			return nil, "", false, signErr
+		}
+	}
+
+	req.Header.Set("Accept", acceptFormats)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	cached, hasCached := cache.lookup(url)
+	if !hasCached {
+		cached, hasCached = settings.readDiskCache(url, logger)
+	}
+
+	if hasCached {
+		cached.apply(req)
+	}
+
+	resp, doRequestErr := fetchClient.Do(req)
 	if doRequestErr != nil {
-		return nil, fmt.Errorf("failed to execute HTTP request: %w", doRequestErr)
+		return nil, "", isTransientError(doRequestErr), fmt.Errorf("failed to execute HTTP request: %w", doRequestErr)
 	}
 
 	defer func() {
@@ -67,25 +302,66 @@ func fetchURL(url string, logger *logger.Logger) ([]byte, error) {
 		}
 	}()
 
-	body, processResponseErr := processResponse(resp)
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		return cached.body, cached.format, false, nil
+	}
+
+	settings.trackProgress(url, resp)
+
+	body, processResponseErr := processResponse(resp, settings.maxBodySize)
+	if errors.Is(processResponseErr, ErrSourceNotFound) {
+		settings.recordMissing(url, logger)
+	}
+
 	if processResponseErr != nil {
-		return nil, fmt.Errorf("failed to process HTTP response: %w", processResponseErr)
+		transient := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode == http.StatusUnauthorized && settings.evictOAuth2Token(req)
+
+		return nil, "", transient, withRetryAfter(resp, fmt.Errorf("failed to process HTTP response: %w", processResponseErr))
 	}
 
-	return body, nil
+	cache.store(url, resp, body)
+	settings.writeDiskCache(url, resp, body, logger)
+
+	return body, documentFormat(resp.Header.Get("Content-Type")), false, nil
 }
 
-// processResponse validates the HTTP response status and reads the response body.
-func processResponse(resp *http.Response) ([]byte, error) {
+// processResponse validates the HTTP response status and reads the response body,
+// decompressing it as its Content-Encoding says and failing with ErrResponseTooLarge
+// when it is longer than limit bytes after decompression.
+func processResponse(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %w: %d", ErrSourceNotFound, ErrUnexpectedHTTPStatus, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("%w: %d", ErrUnexpectedHTTPStatus, resp.StatusCode)
 	}
 
-	body, readAllErr := io.ReadAll(resp.Body)
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	}
+
+	reader, decodeErr := decodedBody(resp)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	body, readAllErr := io.ReadAll(io.LimitReader(reader, limit+1))
+	closeErr := reader.Close()
+
 	if readAllErr != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", readAllErr)
 	}
 
+	if closeErr != nil {
+		return nil, fmt.Errorf("failed to decompress response: %w", closeErr)
+	}
+
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+
 	return body, nil
 }
 
@@ -0,0 +1,98 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/book-expert/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig is the shape of the documents the tests load.
+type testConfig struct {
+	Service struct {
+		Name string `toml:"name"`
+		Port int    `toml:"port"`
+	} `toml:"service"`
+}
+
+// newTestLogger returns a logger writing to a temporary directory.
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+
+	log, logErr := logger.New(t.TempDir(), "configurator.log")
+	require.NoError(t, logErr)
+
+	t.Cleanup(func() {
+		_ = log.Close()
+	})
+
+	return log
+}
+
+// serveDocuments starts a server answering each path in documents with its content and
+// every other path with 404 Not Found.
+func serveDocuments(t *testing.T, documents map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, found := documents[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestLoadFromURL(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\nport = 8080\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, 8080, config.Service.Port)
+}
+
+func TestLoadFromFileMergesEnvironmentOverlay(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.toml"), []byte("[service]\nname = \"tts\"\nport = 8080\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.prod.toml"), []byte("[service]\nport = 443\n"), 0o600))
+	t.Setenv("PROJECT_TOML", filepath.Join(dir, "project.toml"))
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithEnvironment("prod")))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, 443, config.Service.Port)
+}
+
+func TestLoadWithoutProjectTOML(t *testing.T) {
+	t.Setenv("PROJECT_TOML", "")
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrProjectTomlNotSet)
+}
+
+func TestLoadReportsMissingDocument(t *testing.T) {
+	server := serveDocuments(t, nil)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.Error(t, Load(&config, newTestLogger(t)))
+}
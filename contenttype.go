@@ -0,0 +1,81 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// acceptFormats lists the media types requested for configuration documents, preferring
+// TOML and falling back to JSON, YAML, and then anything else.
+const acceptFormats = "application/toml, application/json;q=0.9, application/yaml;q=0.8, */*;q=0.5"
+
+// ErrUnsupportedDocument is returned when a JSON or YAML response cannot be represented
+// as a TOML document.
+var ErrUnsupportedDocument = errors.New("document cannot be converted to TOML")
+
+// documentFormat returns the format, sopsFormatJSON or sopsFormatYAML, that a
+// Content-Type header names, or "" for TOML and unrecognized types.
+func documentFormat(contentType string) string {
+	mediaType, _, parseErr := mime.ParseMediaType(contentType)
+	if parseErr != nil {
+		return ""
+	}
+
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return sopsFormatJSON
+	case mediaType == "application/yaml", mediaType == "application/x-yaml",
+		mediaType == "text/yaml", mediaType == "text/x-yaml", strings.HasSuffix(mediaType, "+yaml"):
+		return sopsFormatYAML
+	default:
+		return ""
+	}
+}
+
+// convertDocument returns body, served in format, as TOML, so the rest of the load
+// handles every format alike. It runs after the pin, lock file, and signatures were
+// checked against the document as served. TOML bodies, and SOPS documents, which are
+// decrypted in their own format, are returned unchanged.
+func convertDocument(body []byte, format string) ([]byte, error) {
+	if format == "" {
+		return body, nil
+	}
+
+	if _, encrypted := sopsFormat(body); encrypted {
+		return body, nil
+	}
+
+	var table map[string]any
+
+	switch format {
+	case sopsFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		decodeErr := decoder.Decode(&table)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to decode JSON document: %w", decodeErr)
+		}
+
+		normalizeJSON(table)
+	default:
+		decodeErr := yaml.Unmarshal(body, &table)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to decode YAML document: %w", decodeErr)
+		}
+	}
+
+	converted, marshalErr := toml.Marshal(table)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedDocument, marshalErr)
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,103 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveTyped serves body with contentType as PROJECT_TOML and returns the Accept header
+// of the last request.
+func serveTyped(t *testing.T, contentType, body string) *string {
+	t.Helper()
+
+	var accept string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project")
+
+	return &accept
+}
+
+func TestDocumentFormat(t *testing.T) {
+	t.Parallel()
+
+	for contentType, want := range map[string]string{
+		"application/json":                sopsFormatJSON,
+		"application/json; charset=utf-8": sopsFormatJSON,
+		"application/vnd.config+json":     sopsFormatJSON,
+		"application/yaml":                sopsFormatYAML,
+		"application/x-yaml":              sopsFormatYAML,
+		"text/yaml":                       sopsFormatYAML,
+		"text/x-yaml":                     sopsFormatYAML,
+		"application/vnd.config+yaml":     sopsFormatYAML,
+		"application/toml":                "",
+		"text/plain":                      "",
+		"":                                "",
+		"not a media type;":               "",
+	} {
+		assert.Equal(t, want, documentFormat(contentType), contentType)
+	}
+}
+
+func TestLoadConvertsJSONAndYAML(t *testing.T) {
+	for contentType, body := range map[string]string{
+		"application/json": `{"service": {"name": "tts", "port": 8080}, "nats": {"url": "nats://localhost"}}`,
+		"application/yaml": "service:\n  name: tts\n  port: 8080\nnats:\n  url: nats://localhost\n",
+	} {
+		t.Run(contentType, func(t *testing.T) {
+			accept := serveTyped(t, contentType, body)
+
+			var config testConfig
+
+			require.NoError(t, Load(&config, newTestLogger(t)))
+			assert.Equal(t, "tts", config.Service.Name)
+			assert.Equal(t, 8080, config.Service.Port)
+			assert.Equal(t, acceptFormats, *accept)
+		})
+	}
+}
+
+func TestConvertDocumentKeepsNumberTypes(t *testing.T) {
+	t.Parallel()
+
+	converted, convertErr := convertDocument([]byte(`{"count": 3, "ratio": 0.5, "big": 9007199254740993}`), sopsFormatJSON)
+	require.NoError(t, convertErr)
+
+	var table map[string]any
+
+	require.NoError(t, toml.Unmarshal(converted, &table))
+	assert.Equal(t, map[string]any{"count": int64(3), "ratio": 0.5, "big": int64(9007199254740993)}, table)
+}
+
+func TestConvertDocumentLeavesTOMLUnchanged(t *testing.T) {
+	t.Parallel()
+
+	body := []byte("[service]\nname = \"tts\"\n")
+
+	converted, convertErr := convertDocument(body, "")
+	require.NoError(t, convertErr)
+	assert.Equal(t, body, converted)
+}
+
+func TestConvertDocumentRejectsInvalidDocuments(t *testing.T) {
+	t.Parallel()
+
+	_, unsupportedErr := convertDocument([]byte(`{"hosts": [null]}`), sopsFormatJSON)
+	require.ErrorIs(t, unsupportedErr, ErrUnsupportedDocument)
+
+	_, arrayErr := convertDocument([]byte(`[1, 2]`), sopsFormatJSON)
+	require.ErrorContains(t, arrayErr, "JSON")
+
+	_, yamlErr := convertDocument([]byte("- 1\n- 2\n"), sopsFormatYAML)
+	require.ErrorContains(t, yamlErr, "YAML")
+}
@@ -0,0 +1,123 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultPrepareTimeout bounds a participant's Prepare callback when it sets no Timeout.
+const DefaultPrepareTimeout = 30 * time.Second
+
+// ErrReloadAborted is returned when a participant fails to prepare for a new configuration.
+var ErrReloadAborted = errors.New("configuration reload aborted")
+
+// Participant is a component that takes part in coordinated reloads. Prepare is called
+// with the incoming configuration so the component can drain work; it must return
+// before Timeout or its context is cancelled. Commit is called once the new configuration
+// is applied. Abort, when set, is called if the reload is abandoned after Prepare succeeded.
+type Participant[T any] struct {
+	Name    string
+	Timeout time.Duration
+	Prepare func(ctx context.Context, next *T) error
+	Commit  func(next *T)
+	Abort   func()
+}
+
+// Coordinator applies new configurations in two phases: every registered participant
+// prepares concurrently, then the configuration is applied atomically and each
+// participant commits. It is safe for concurrent use.
+type Coordinator[T any] struct {
+	mu           sync.Mutex
+	participants []Participant[T]
+}
+
+// NewCoordinator returns a Coordinator with no participants.
+func NewCoordinator[T any]() *Coordinator[T] {
+	return &Coordinator[T]{}
+}
+
+// Register adds participant to every subsequent reload.
+func (c *Coordinator[T]) Register(participant Participant[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.participants = append(c.participants, participant)
+}
+
+// Apply prepares every participant for next, calls apply, and then commits the
+// participants in registration order. If any Prepare fails, apply is not called,
+// participants that prepared successfully are aborted, and the error wraps
+// ErrReloadAborted.
+func (c *Coordinator[T]) Apply(ctx context.Context, next *T, apply func()) error {
+	c.mu.Lock()
+	participants := append([]Participant[T](nil), c.participants...)
+	c.mu.Unlock()
+
+	prepareErrs := make([]error, len(participants))
+
+	var wg sync.WaitGroup
+
+	for index, participant := range participants {
+		wg.Go(func() {
+			prepareErrs[index] = prepareParticipant(ctx, participant, next)
+		})
+	}
+
+	wg.Wait()
+
+	failure := errors.Join(prepareErrs...)
+	if failure != nil {
+		for index, participant := range participants {
+			if prepareErrs[index] == nil && participant.Abort != nil {
+				participant.Abort()
+			}
+		}
+
+		return fmt.Errorf("%w: %w", ErrReloadAborted, failure)
+	}
+
+	apply()
+
+	for _, participant := range participants {
+		if participant.Commit != nil {
+			participant.Commit(next)
+		}
+	}
+
+	return nil
+}
+
+// prepareParticipant runs participant's Prepare callback under its timeout.
+func prepareParticipant[T any](ctx context.Context, participant Participant[T], next *T) error {
+	if participant.Prepare == nil {
+		return nil
+	}
+
+	timeout := participant.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPrepareTimeout
+	}
+
+	prepareCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- participant.Prepare(prepareCtx, next)
+	}()
+
+	select {
+	case prepareErr := <-done:
+		if prepareErr != nil {
+			return fmt.Errorf("participant %s failed to prepare: %w", participant.Name, prepareErr)
+		}
+
+		return nil
+	case <-prepareCtx.Done():
+		return fmt.Errorf("participant %s did not prepare in time: %w", participant.Name, prepareCtx.Err())
+	}
+}
@@ -0,0 +1,132 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coordinatorLog records the callbacks a coordinated reload made, in order.
+type coordinatorLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+// add appends entry.
+func (l *coordinatorLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+}
+
+// participant returns a participant named name that logs its callbacks and fails to
+// prepare with prepareErr.
+func (l *coordinatorLog) participant(name string, prepareErr error) Participant[testConfig] {
+	return Participant[testConfig]{
+		Name: name,
+		Prepare: func(context.Context, *testConfig) error {
+			return prepareErr
+		},
+		Commit: func(*testConfig) { l.add("commit " + name) },
+		Abort:  func() { l.add("abort " + name) },
+	}
+}
+
+func TestCoordinatorAppliesAndCommitsInOrder(t *testing.T) {
+	t.Parallel()
+
+	var log coordinatorLog
+
+	coordinator := NewCoordinator[testConfig]()
+	coordinator.Register(log.participant("first", nil))
+	coordinator.Register(log.participant("second", nil))
+	coordinator.Register(Participant[testConfig]{Name: "passive"})
+
+	require.NoError(t, coordinator.Apply(context.Background(), &testConfig{}, func() { log.add("apply") }))
+	assert.Equal(t, []string{"apply", "commit first", "commit second"}, log.entries)
+}
+
+func TestCoordinatorAbortsPreparedParticipantsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	var log coordinatorLog
+
+	busy := errors.New("busy")
+
+	coordinator := NewCoordinator[testConfig]()
+	coordinator.Register(log.participant("first", nil))
+	coordinator.Register(log.participant("failing", busy))
+	coordinator.Register(log.participant("third", nil))
+
+	applyErr := coordinator.Apply(context.Background(), &testConfig{}, func() { log.add("apply") })
+
+	require.ErrorIs(t, applyErr, ErrReloadAborted)
+	require.ErrorIs(t, applyErr, busy)
+	assert.ElementsMatch(t, []string{"abort first", "abort third"}, log.entries)
+}
+
+func TestCoordinatorBoundsPrepareByTimeout(t *testing.T) {
+	t.Parallel()
+
+	coordinator := NewCoordinator[testConfig]()
+	coordinator.Register(Participant[testConfig]{
+		Name:    "stuck",
+		Timeout: 10 * time.Millisecond,
+		Prepare: func(ctx context.Context, _ *testConfig) error {
+			<-ctx.Done()
+			time.Sleep(time.Second)
+
+			return nil
+		},
+	})
+
+	applied := false
+	started := time.Now()
+
+	applyErr := coordinator.Apply(context.Background(), &testConfig{}, func() { applied = true })
+
+	require.ErrorIs(t, applyErr, ErrReloadAborted)
+	require.ErrorIs(t, applyErr, context.DeadlineExceeded)
+	assert.False(t, applied)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestCoordinatorPreparesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	var arrived sync.WaitGroup
+
+	arrived.Add(2)
+
+	// Each participant waits for the other to start preparing, which only happens when
+	// they prepare at the same time.
+	rendezvous := func(ctx context.Context, _ *testConfig) error {
+		arrived.Done()
+
+		waited := make(chan struct{})
+
+		go func() {
+			arrived.Wait()
+			close(waited)
+		}()
+
+		select {
+		case <-waited:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	coordinator := NewCoordinator[testConfig]()
+	coordinator.Register(Participant[testConfig]{Name: "a", Timeout: time.Second, Prepare: rendezvous})
+	coordinator.Register(Participant[testConfig]{Name: "b", Timeout: time.Second, Prepare: rendezvous})
+
+	require.NoError(t, coordinator.Apply(context.Background(), &testConfig{}, func() {}))
+}
@@ -0,0 +1,75 @@
+package configurator
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// cosignSignatureSuffix is appended to the path of the source to locate its cosign signature.
+const cosignSignatureSuffix = ".sig"
+
+// ErrInvalidPublicKey is returned when the cosign public key is not a PEM-encoded ECDSA key.
+var ErrInvalidPublicKey = errors.New("invalid cosign public key")
+
+// WithCosignPublicKey requires every configuration document, the base document as well
+// as its includes and overlays, to carry a cosign blob signature, read from its source
+// with .sig appended to the path, made with the private half of the PEM-encoded ECDSA public key at
+// path, as produced by cosign sign-blob --key.
+func WithCosignPublicKey(path string) Option {
+	return func(o *options) {
+		o.cosignPublicKey = path
+	}
+}
+
+// checkCosignSignature checks content, read from source, against its base64-encoded
+// cosign signature.
+func checkCosignSignature(settings *options, source string, content, encoded []byte) error {
+	publicKey, keyErr := readCosignPublicKey(settings.cosignPublicKey)
+	if keyErr != nil {
+		return keyErr
+	}
+
+	signature, decodeErr := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if decodeErr != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSignature, source, decodeErr)
+	}
+
+	digest := sha256.Sum256(content)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, source)
+	}
+
+	return nil
+}
+
+// readCosignPublicKey reads the PEM-encoded ECDSA public key at path.
+func readCosignPublicKey(path string) (*ecdsa.PublicKey, error) {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read public key %s: %w", path, readErr)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPublicKey, path)
+	}
+
+	parsed, parseErr := x509.ParsePKIXPublicKey(block.Bytes)
+	if parseErr != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPublicKey, path, parseErr)
+	}
+
+	publicKey, isECDSA := parsed.(*ecdsa.PublicKey)
+	if !isECDSA {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPublicKey, path)
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,145 @@
+package configurator
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cosignKey returns a new ECDSA P-256 key and the path of the PEM file holding its
+// public part, as cosign generate-key-pair writes it.
+func cosignKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	privateKey, generateErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, generateErr)
+
+	return privateKey, writePublicKey(t, &privateKey.PublicKey)
+}
+
+// writePublicKey writes publicKey as a PEM-encoded PKIX key and returns its path.
+func writePublicKey(t *testing.T, publicKey any) string {
+	t.Helper()
+
+	encoded, marshalErr := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, marshalErr)
+
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encoded}), 0o600))
+
+	return path
+}
+
+// cosignSign returns the base64-encoded signature cosign sign-blob makes of document.
+func cosignSign(t *testing.T, privateKey *ecdsa.PrivateKey, document string) string {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte(document))
+
+	signature, signErr := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	require.NoError(t, signErr)
+
+	return base64.StdEncoding.EncodeToString(signature) + "\n"
+}
+
+// cosignDocuments returns documents with a .sig signature by privateKey for every one
+// of them.
+func cosignDocuments(t *testing.T, privateKey *ecdsa.PrivateKey, documents map[string]string) map[string]string {
+	t.Helper()
+
+	signed := make(map[string]string, 2*len(documents))
+
+	for path, document := range documents {
+		signed[path] = document
+		signed[path+cosignSignatureSuffix] = cosignSign(t, privateKey, document)
+	}
+
+	return signed
+}
+
+func TestLoadVerifiesCosignSignatures(t *testing.T) {
+	privateKey, publicKey := cosignKey(t)
+	server := serveDocuments(t, cosignDocuments(t, privateKey, layeredDocuments))
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithCosignPublicKey(publicKey), WithEnvironment("prod")))
+	assert.Equal(t, map[string]any{"name": "tts", "port": int64(443)}, config["service"])
+}
+
+func TestLoadVerifiesCosignSignatureOfURLWithQuery(t *testing.T) {
+	privateKey, publicKey := cosignKey(t)
+	server := serveDocuments(t, cosignDocuments(t, privateKey, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	}))
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml?ref=main")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithCosignPublicKey(publicKey)))
+	assert.Equal(t, map[string]any{"name": "tts"}, config["service"])
+	assert.Equal(t, server.URL+"/project.toml.sig?ref=main", signatureSource(server.URL+"/project.toml?ref=main", cosignSignatureSuffix))
+}
+
+func TestLoadRejectsBadCosignSignatures(t *testing.T) {
+	privateKey, publicKey := cosignKey(t)
+	otherKey, _ := cosignKey(t)
+
+	tests := map[string]func(documents map[string]string){
+		"tampered include": func(documents map[string]string) {
+			documents["/nats.toml"] += "injected = true\n"
+		},
+		"missing overlay signature": func(documents map[string]string) {
+			delete(documents, "/project.prod.toml"+cosignSignatureSuffix)
+		},
+		"signature by another key": func(documents map[string]string) {
+			documents["/project.toml"+cosignSignatureSuffix] = cosignSign(t, otherKey, documents["/project.toml"])
+		},
+		"malformed signature": func(documents map[string]string) {
+			documents["/project.toml"+cosignSignatureSuffix] = "not base64!"
+		},
+	}
+
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			documents := cosignDocuments(t, privateKey, layeredDocuments)
+			tamper(documents)
+
+			server := serveDocuments(t, documents)
+			t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+			var config map[string]any
+
+			loadErr := Load(&config, newTestLogger(t), WithCosignPublicKey(publicKey), WithEnvironment("prod"))
+			require.ErrorIs(t, loadErr, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestReadCosignPublicKeyRejectsOtherKeys(t *testing.T) {
+	t.Parallel()
+
+	edPublic, _, generateErr := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, generateErr)
+
+	_, keyErr := readCosignPublicKey(writePublicKey(t, edPublic))
+	require.ErrorIs(t, keyErr, ErrInvalidPublicKey)
+
+	notPEM := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a key"), 0o600))
+
+	_, pemErr := readCosignPublicKey(notPEM)
+	require.ErrorIs(t, pemErr, ErrInvalidPublicKey)
+}
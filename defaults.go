@@ -0,0 +1,37 @@
+package configurator
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// WithDefaults merges the TOML document at path in fsys as LayerDefaults, the lowest
+// precedence layer, so a service can ship built-in defaults, typically from an
+// embed.FS, that other layers only need to override.
+func WithDefaults(fsys fs.FS, path string) Option {
+	return func(o *options) {
+		o.defaultsFS = fsys
+		o.defaultsPath = path
+	}
+}
+
+// collectDefaults reads the built-in defaults as the LayerDefaults layer.
+func collectDefaults(state *assembly) error {
+	if state.settings.defaultsFS == nil {
+		return nil
+	}
+
+	content, readErr := fs.ReadFile(state.settings.defaultsFS, state.settings.defaultsPath)
+	if readErr != nil {
+		return fmt.Errorf("failed to read defaults %s: %w", state.settings.defaultsPath, readErr)
+	}
+
+	table, parseErr := parseTable(content, state.settings)
+	if parseErr != nil {
+		return fmt.Errorf("failed to parse defaults %s: %w", state.settings.defaultsPath, parseErr)
+	}
+
+	state.setLayer(LayerDefaults, table)
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package configurator
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultsFS holds the built-in defaults of the tests.
+var defaultsFS = fstest.MapFS{
+	"defaults.toml": {Data: []byte("[service]\nname = \"default\"\nport = 80\n\n[nats]\nurl = \"nats://localhost:4222\"\n")},
+	"broken.toml":   {Data: []byte("[service\n")},
+}
+
+func TestLoadMergesDefaultsBeneathEverything(t *testing.T) {
+	localDocument(t, "[service]\nport = 8080\n")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithDefaults(defaultsFS, "defaults.toml")))
+	assert.Equal(t, map[string]any{"name": "default", "port": int64(8080)}, config["service"])
+	assert.Equal(t, map[string]any{"url": "nats://localhost:4222"}, config["nats"])
+}
+
+func TestLoadRejectsUnreadableDefaults(t *testing.T) {
+	localDocument(t, "[service]\nport = 8080\n")
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithDefaults(defaultsFS, "missing.toml")), fs.ErrNotExist)
+	require.ErrorContains(t, Load(&config, newTestLogger(t), WithDefaults(defaultsFS, "broken.toml")), "broken.toml")
+}
@@ -0,0 +1,84 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// changedKeys returns the sorted dotted key paths whose values differ between the two
+// TOML documents, including keys that were added or removed. Arrays are compared whole.
+func changedKeys(previous, current []byte) ([]string, error) {
+	var previousDoc, currentDoc map[string]any
+
+	previousErr := toml.Unmarshal(previous, &previousDoc)
+	if previousErr != nil {
+		return nil, fmt.Errorf("failed to parse previous configuration: %w", previousErr)
+	}
+
+	currentErr := toml.Unmarshal(current, &currentDoc)
+	if currentErr != nil {
+		return nil, fmt.Errorf("failed to parse current configuration: %w", currentErr)
+	}
+
+	var changed []string
+
+	diffTables("", previousDoc, currentDoc, &changed)
+	slices.Sort(changed)
+
+	return changed, nil
+}
+
+// diffTables appends to changed every key path below prefix that differs between the tables.
+func diffTables(prefix string, previous, current map[string]any, changed *[]string) {
+	for key, previousValue := range previous {
+		path := joinKey(prefix, key)
+
+		currentValue, found := current[key]
+		if !found {
+			*changed = append(*changed, path)
+
+			continue
+		}
+
+		previousTable, previousIsTable := previousValue.(map[string]any)
+		currentTable, currentIsTable := currentValue.(map[string]any)
+
+		switch {
+		case previousIsTable && currentIsTable:
+			diffTables(path, previousTable, currentTable, changed)
+		case !reflect.DeepEqual(previousValue, currentValue):
+			*changed = append(*changed, path)
+		}
+	}
+
+	for key := range current {
+		if _, found := previous[key]; !found {
+			*changed = append(*changed, joinKey(prefix, key))
+		}
+	}
+}
+
+// joinKey appends key to the dotted path prefix.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// Changed reports whether key, or any key nested below it, is among the update's
+// ChangedKeys; for example Changed("nats") is true when "nats.url" changed.
+func (u Update[T]) Changed(key string) bool {
+	for _, changed := range u.ChangedKeys {
+		if changed == key || strings.HasPrefix(changed, key+".") || strings.HasPrefix(key, changed+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,218 @@
+package configurator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+const (
+	// CacheDirVariable names the environment variable holding the directory fetched
+	// documents are cached in, used when WithCacheDir is not given.
+	CacheDirVariable = "PROJECT_TOML_CACHE_DIR"
+	// cacheDirMode keeps cached documents, which may hold secrets, private to their
+	// owner; the entries themselves are created with mode 0600.
+	cacheDirMode = 0o700
+	// cacheFileExtension is the extension of the cache entry files.
+	cacheFileExtension = ".json"
+)
+
+// WithCacheDir caches fetched documents with their ETag and Last-Modified validators in
+// dir, so later loads, even by a new process, send conditional requests and reuse the
+// cached copy on 304 Not Modified. Entries are encrypted with the key of WithCacheKey, or
+// the secret key; without either, loads fail with ErrCacheKeyNotSet unless
+// WithPlaintextCache allows unencrypted entries.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// diskEntry is the on-disk form of a cached response. Format is the document format the
+// Content-Type named. Sealed holds the body encrypted with the cache key, and Body is
+// set instead only when plaintext entries are allowed.
+// Missing marks a document that answered 404.
+type diskEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Format       string    `json:"format,omitempty"`
+	Body         []byte    `json:"body,omitempty"`
+	Sealed       string    `json:"sealed,omitempty"`
+	Missing      bool      `json:"missing,omitempty"`
+}
+
+// diskCachePath returns the file caching url.
+func (o *options) diskCachePath(url string) string {
+	digest := sha256.Sum256([]byte(url))
+
+	return filepath.Join(o.cacheDir, hex.EncodeToString(digest[:])+cacheFileExtension)
+}
+
+// readDiskCache returns the response cached on disk for url. Unreadable entries,
+// encrypted ones without the key, and refused plaintext ones are logged and treated as
+// missing.
+func (o *options) readDiskCache(url string, logger *logger.Logger) (cachedResponse, bool) {
+	entry, found, readErr := o.readDiskEntry(url)
+	if readErr != nil {
+		logger.Warn("ignoring cached copy of %s: %v", url, readErr)
+
+		return cachedResponse{}, false
+	}
+
+	if !found || entry.Missing {
+		return cachedResponse{}, false
+	}
+
+	return cachedResponse{etag: entry.ETag, lastModified: entry.LastModified, body: entry.Body, format: entry.Format}, true
+}
+
+// readDiskEntry reads and decrypts the cache entry for url.
+func (o *options) readDiskEntry(url string) (diskEntry, bool, error) {
+	if o.cacheDir == "" {
+		return diskEntry{}, false, nil
+	}
+
+	content, readErr := os.ReadFile(o.diskCachePath(url))
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return diskEntry{}, false, nil
+	}
+
+	if readErr != nil {
+		return diskEntry{}, false, fmt.Errorf("failed to read cache entry: %w", readErr)
+	}
+
+	var entry diskEntry
+
+	unmarshalErr := json.Unmarshal(content, &entry)
+	if unmarshalErr != nil {
+		return diskEntry{}, false, fmt.Errorf("failed to decode cache entry: %w", unmarshalErr)
+	}
+
+	if entry.URL != url {
+		return diskEntry{}, false, nil
+	}
+
+	if entry.Missing {
+		return entry, true, nil
+	}
+
+	body, openErr := o.openCopy(entry.Sealed, entry.Body)
+	if openErr != nil {
+		return diskEntry{}, false, openErr
+	}
+
+	entry.Body, entry.Sealed = body, ""
+
+	return entry, true, nil
+}
+
+// writeDiskCache caches a successful response for url on disk. Failures are logged,
+// since the fetch itself succeeded.
+func (o *options) writeDiskCache(url string, resp *http.Response, body []byte, logger *logger.Logger) {
+	if o.cacheDir == "" {
+		return
+	}
+
+	writeErr := o.writeDiskEntry(diskEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+		Format:       documentFormat(resp.Header.Get("Content-Type")),
+		Body:         body,
+	})
+	if writeErr != nil {
+		logger.Warn("failed to cache %s: %v", url, writeErr)
+	}
+}
+
+// recordMissing replaces the cache entry for url with a marker that the document does
+// not exist, so the fallback and offline mode treat it as absent rather than failing or
+// bringing back a deleted copy.
+func (o *options) recordMissing(url string, logger *logger.Logger) {
+	if o.cacheDir == "" {
+		return
+	}
+
+	writeErr := o.writeDiskEntry(diskEntry{URL: url, FetchedAt: time.Now(), Missing: true})
+	if writeErr != nil {
+		logger.Warn("failed to cache %s: %v", url, writeErr)
+	}
+}
+
+// writeDiskEntry encrypts entry with the cache key, unless plaintext entries are allowed
+// and no key is configured, and replaces the cache file atomically.
+func (o *options) writeDiskEntry(entry diskEntry) error {
+	if !entry.Missing {
+		aead, keyErr := o.cacheCipher()
+		if keyErr != nil {
+			return keyErr
+		}
+
+		sealed, sealErr := sealCopy(aead, entry.Body)
+		if sealErr != nil {
+			return sealErr
+		}
+
+		if sealed != "" {
+			entry.Body, entry.Sealed = nil, sealed
+		}
+	}
+
+	content, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", marshalErr)
+	}
+
+	mkdirErr := os.MkdirAll(o.cacheDir, cacheDirMode)
+	if mkdirErr != nil {
+		return fmt.Errorf("failed to create cache directory: %w", mkdirErr)
+	}
+
+	writeErr := writeFileAtomic(o.diskCachePath(entry.URL), content)
+	if writeErr != nil {
+		return fmt.Errorf("failed to write cache entry: %w", writeErr)
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with content, readable and writable only by
+// its owner, by writing a temporary file in the same directory and renaming it, so
+// readers never see a partial file.
+func writeFileAtomic(path string, content []byte) error {
+	temporary, createErr := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if createErr != nil {
+		return fmt.Errorf("failed to create temporary file: %w", createErr)
+	}
+
+	_, writeErr := temporary.Write(content)
+	closeErr := temporary.Close()
+
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if writeErr == nil {
+		writeErr = os.Rename(temporary.Name(), path)
+	}
+
+	if writeErr != nil {
+		removeErr := os.Remove(temporary.Name())
+
+		return errors.Join(writeErr, removeErr)
+	}
+
+	return nil
+}
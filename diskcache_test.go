@@ -0,0 +1,91 @@
+package configurator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheKey is a fixed AES-256 key for the disk cache tests.
+var cacheKey = bytes.Repeat([]byte{0x42}, 32)
+
+// cachedFiles returns the contents of every file in dir.
+func cachedFiles(t *testing.T, dir string) [][]byte {
+	t.Helper()
+
+	entries, readDirErr := os.ReadDir(dir)
+	require.NoError(t, readDirErr)
+
+	contents := make([][]byte, 0, len(entries))
+
+	for _, entry := range entries {
+		content, readErr := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, readErr)
+
+		contents = append(contents, content)
+	}
+
+	return contents
+}
+
+func TestDiskCacheEncryptsDocuments(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	dir := t.TempDir()
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithCacheDir(dir), WithCacheKey(cacheKey)))
+
+	files := cachedFiles(t, dir)
+	require.NotEmpty(t, files)
+
+	for _, content := range files {
+		assert.NotContains(t, string(content), "tts")
+	}
+
+	server.Close()
+
+	var offline testConfig
+
+	require.NoError(t, Load(&offline, newTestLogger(t), WithCacheDir(dir), WithCacheKey(cacheKey), WithOffline(true)))
+	assert.Equal(t, "tts", offline.Service.Name)
+
+	wrongKey := bytes.Repeat([]byte{0x24}, 32)
+	require.Error(t, Load(&offline, newTestLogger(t), WithCacheDir(dir), WithCacheKey(wrongKey), WithOffline(true)))
+}
+
+func TestDiskCacheFailsClosedWithoutKey(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+	t.Setenv(CacheKeyVariable, "")
+	t.Setenv(SecretKeyVariable, "")
+	t.Setenv(PlaintextCacheVariable, "")
+
+	dir := t.TempDir()
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithCacheDir(dir)), ErrCacheKeyNotSet)
+	assert.Empty(t, cachedFiles(t, dir))
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithCacheDir(dir), WithPlaintextCache(true)))
+	assert.Equal(t, "tts", config.Service.Name)
+
+	server.Close()
+
+	var offline testConfig
+
+	require.Error(t, Load(&offline, newTestLogger(t), WithCacheDir(dir), WithCacheKey(cacheKey), WithOffline(true)))
+	require.NoError(t, Load(&offline, newTestLogger(t), WithCacheDir(dir), WithPlaintextCache(true), WithOffline(true)))
+	assert.Equal(t, "tts", offline.Service.Name)
+}
@@ -0,0 +1,134 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// envPrefixKey is the table key naming the prefix of the environment variables that
+// override the table's values. The leading underscore keeps it apart from ordinary
+// settings named env_prefix.
+const envPrefixKey = "_env_prefix"
+
+var (
+	// ErrInvalidEnvPrefix is returned when _env_prefix is not a string.
+	ErrInvalidEnvPrefix = errors.New("_env_prefix must be a string")
+	// ErrInvalidEnvOverride is returned when an environment variable cannot be parsed as
+	// the type of the value it overrides.
+	ErrInvalidEnvOverride = errors.New("invalid environment override")
+)
+
+// applyEnvPrefixes overrides the values of every table declaring an _env_prefix with the
+// matching environment variables: with _env_prefix = "NATS_", NATS_URL replaces url and
+// NATS_TLS_CERT replaces tls.cert. Key names are upper-cased with dashes turned into
+// underscores. Only keys present in the configuration are overridden, and the variable is
+// parsed as a TOML value unless the key holds a string. The _env_prefix keys are removed
+// from the result; the boolean result reports whether any were found.
+func applyEnvPrefixes(root map[string]any) (map[string]any, bool, error) {
+	overrider := &envOverrider{}
+
+	overridden, overrideErr := overrider.table("", root, "")
+	if overrideErr != nil {
+		return nil, false, overrideErr
+	}
+
+	return overridden, overrider.found, nil
+}
+
+// envOverrider applies environment overrides. found records whether any _env_prefix was
+// seen.
+type envOverrider struct {
+	found bool
+}
+
+// table returns a copy of table with its values overridden by the variables starting
+// with prefix. An _env_prefix in the table replaces the inherited prefix.
+func (o *envOverrider) table(path string, table map[string]any, prefix string) (map[string]any, error) {
+	if raw, declared := table[envPrefixKey]; declared {
+		o.found = true
+
+		declaredPrefix, isString := raw.(string)
+		if !isString {
+			return nil, fmt.Errorf("%s: %w", path, ErrInvalidEnvPrefix)
+		}
+
+		prefix = declaredPrefix
+	}
+
+	overridden := make(map[string]any, len(table))
+
+	for key, value := range table {
+		if key == envPrefixKey {
+			continue
+		}
+
+		name := ""
+		if prefix != "" {
+			name = prefix + envName(key)
+		}
+
+		if nested, isTable := value.(map[string]any); isTable {
+			nestedPrefix := ""
+			if name != "" {
+				nestedPrefix = name + "_"
+			}
+
+			resolved, nestedErr := o.table(joinKey(path, key), nested, nestedPrefix)
+			if nestedErr != nil {
+				return nil, nestedErr
+			}
+
+			overridden[key] = resolved
+
+			continue
+		}
+
+		overridden[key] = value
+
+		if name == "" {
+			continue
+		}
+
+		raw, set := os.LookupEnv(name)
+		if !set {
+			continue
+		}
+
+		parsed, parseErr := parseEnvValue(raw, value)
+		if parseErr != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidEnvOverride, name, parseErr)
+		}
+
+		overridden[key] = parsed
+	}
+
+	return overridden, nil
+}
+
+// envName converts a TOML key to the environment variable suffix that overrides it.
+func envName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// parseEnvValue parses raw as a value of the same kind as current. Strings are taken
+// verbatim; anything else is parsed as a TOML value, e.g. 42, true, or ["a", "b"].
+func parseEnvValue(raw string, current any) (any, error) {
+	if _, isString := current.(string); isString {
+		return raw, nil
+	}
+
+	var parsed struct {
+		Value any `toml:"value"`
+	}
+
+	unmarshalErr := toml.Unmarshal([]byte("value = "+raw), &parsed)
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", raw, unmarshalErr)
+	}
+
+	return parsed.Value, nil
+}
@@ -0,0 +1,89 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envPrefixDocument declares environment prefixes on nested tables of every value type.
+const envPrefixDocument = `[nats]
+_env_prefix = "NATS_"
+url = "nats://localhost:4222"
+max-reconnects = 5
+verbose = false
+ratio = 0.5
+servers = ["a"]
+version = "1"
+
+[nats.tls]
+cert = "/etc/cert.pem"
+
+[nats.auth]
+_env_prefix = "AUTH_"
+user = "nats"
+
+[db]
+url = "postgres://localhost"
+`
+
+func TestLoadAppliesEnvironmentOverridesByType(t *testing.T) {
+	localDocument(t, envPrefixDocument)
+	t.Setenv("NATS_URL", "nats://prod:4222")
+	t.Setenv("NATS_MAX_RECONNECTS", "10")
+	t.Setenv("NATS_VERBOSE", "true")
+	t.Setenv("NATS_RATIO", "0.75")
+	t.Setenv("NATS_SERVERS", `["b", "c"]`)
+	t.Setenv("NATS_VERSION", "2")
+	t.Setenv("NATS_TLS_CERT", "/run/cert.pem")
+	t.Setenv("AUTH_USER", "service")
+	t.Setenv("NATS_AUTH_USER", "ignored")
+	t.Setenv("NATS_UNKNOWN", "ignored")
+	t.Setenv("DB_URL", "ignored")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, map[string]any{
+		"url":            "nats://prod:4222",
+		"max-reconnects": int64(10),
+		"verbose":        true,
+		"ratio":          0.75,
+		"servers":        []any{"b", "c"},
+		"version":        "2",
+		"tls":            map[string]any{"cert": "/run/cert.pem"},
+		"auth":           map[string]any{"user": "service"},
+	}, config["nats"])
+	assert.Equal(t, map[string]any{"url": "postgres://localhost"}, config["db"])
+}
+
+func TestLoadRejectsUnparsableEnvironmentOverrides(t *testing.T) {
+	localDocument(t, envPrefixDocument)
+	t.Setenv("NATS_MAX_RECONNECTS", "ten")
+
+	var config map[string]any
+
+	loadErr := Load(&config, newTestLogger(t))
+	require.ErrorIs(t, loadErr, ErrInvalidEnvOverride)
+	assert.ErrorContains(t, loadErr, "NATS_MAX_RECONNECTS")
+}
+
+func TestLoadRejectsNonStringEnvPrefix(t *testing.T) {
+	localDocument(t, "[nats]\n_env_prefix = 5\nurl = \"nats://localhost\"\n")
+
+	var config map[string]any
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrInvalidEnvPrefix)
+}
+
+func TestApplyEnvPrefixesLeavesTablesWithoutPrefix(t *testing.T) {
+	t.Setenv("URL", "ignored")
+
+	root := map[string]any{"url": "nats://localhost", "nats": map[string]any{"url": "x"}}
+
+	overridden, found, overrideErr := applyEnvPrefixes(root)
+	require.NoError(t, overrideErr)
+	assert.False(t, found)
+	assert.Equal(t, root, overridden)
+}
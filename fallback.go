@@ -0,0 +1,118 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+const (
+	// OfflineVariable names the environment variable that, when true, serves remote
+	// documents from the disk cache without touching the network, used when WithOffline
+	// is not given.
+	OfflineVariable = "PROJECT_TOML_OFFLINE"
+	// MaxStaleVariable names the environment variable that enables the last-known-good
+	// fallback with the given maximum age, a Go duration where 0 means any age, used
+	// when WithStaleFallback is not given.
+	MaxStaleVariable = "PROJECT_TOML_MAX_STALE"
+	// cacheSubdirectory is the directory below the user cache directory used when the
+	// fallback or offline mode needs a cache and none is configured.
+	cacheSubdirectory = "book-expert/configurator"
+)
+
+// ErrOffline is returned in offline mode when a remote document has no cached copy. It
+// wraps ErrSourceNotFound, so optional layers that were never fetched count as absent.
+var ErrOffline = errors.New("no cached copy available offline")
+
+// WithOffline serves remote documents from the disk cache without touching the network,
+// failing with ErrOffline for those never fetched before.
+func WithOffline(offline bool) Option {
+	return func(o *options) {
+		o.offline = offline
+	}
+}
+
+// WithStaleFallback serves the last successfully fetched copy of a remote document, with
+// a warning, when fetching it fails transiently. Copies older than maxAge are not used;
+// zero accepts any age.
+func WithStaleFallback(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.staleFallback = true
+		o.maxStale = maxAge
+	}
+}
+
+// defaultOffline reports whether OfflineVariable is set to true.
+func defaultOffline() bool {
+	offline, _ := strconv.ParseBool(os.Getenv(OfflineVariable))
+
+	return offline
+}
+
+// defaultStaleFallback reads the fallback setting from MaxStaleVariable.
+func defaultStaleFallback() (bool, time.Duration) {
+	maxAge, parseErr := time.ParseDuration(os.Getenv(MaxStaleVariable))
+	if parseErr != nil || maxAge < 0 {
+		return false, 0
+	}
+
+	return true, maxAge
+}
+
+// defaultCacheDir returns the cache directory used by the fallback and offline mode
+// when WithCacheDir is not given.
+func defaultCacheDir() string {
+	dir, dirErr := os.UserCacheDir()
+	if dirErr != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, cacheSubdirectory)
+}
+
+// offlineCopy returns the cached copy of url, and its format, in offline mode.
+func (o *options) offlineCopy(url string, logger *logger.Logger) ([]byte, string, error) {
+	entry, found, readErr := o.readDiskEntry(url)
+	if readErr != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrOffline, readErr)
+	}
+
+	if !found {
+		return nil, "", fmt.Errorf("%w: %w", ErrOffline, ErrSourceNotFound)
+	}
+
+	if entry.Missing {
+		return nil, "", ErrSourceNotFound
+	}
+
+	logger.Info("offline: using copy of %s fetched at %s", url, entry.FetchedAt.Format(time.RFC3339))
+
+	return entry.Body, entry.Format, nil
+}
+
+// staleCopy returns the last-known-good copy of url, and its format, after fetching it
+// failed with fetchErr, or fetchErr when there is no copy recent enough.
+func (o *options) staleCopy(url string, fetchErr error, logger *logger.Logger) ([]byte, string, error) {
+	entry, found, readErr := o.readDiskEntry(url)
+	if readErr != nil || !found {
+		return nil, "", fetchErr
+	}
+
+	if entry.Missing {
+		return nil, "", ErrSourceNotFound
+	}
+
+	age := time.Since(entry.FetchedAt)
+	if o.maxStale > 0 && age > o.maxStale {
+		return nil, "", fmt.Errorf("%w (cached copy is %s old)", fetchErr, age.Round(time.Second))
+	}
+
+	logger.Warn("using copy of %s fetched %s ago: %v", url, age.Round(time.Second), fetchErr)
+
+	return entry.Body, entry.Format, nil
+}
@@ -0,0 +1,135 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer serves project.toml until it is told to answer with an error status.
+type flakyServer struct {
+	*httptest.Server
+	status   atomic.Int32
+	requests atomic.Int32
+}
+
+// newFlakyServer starts a flakyServer and points PROJECT_TOML at it.
+func newFlakyServer(t *testing.T) *flakyServer {
+	t.Helper()
+
+	server := &flakyServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.requests.Add(1)
+
+		if status := int(server.status.Load()); status != 0 {
+			w.WriteHeader(status)
+
+			return
+		}
+
+		if r.URL.Path != "/project.toml" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("[service]\nname = \"tts\"\nport = 8080\n"))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	return server
+}
+
+// ageCachedCopy moves the fetch time of the cached copy of url back by age.
+func ageCachedCopy(t *testing.T, dir, url string, age time.Duration) {
+	t.Helper()
+
+	settings := newOptions([]Option{WithCacheDir(dir), WithCacheKey(cacheKey)})
+
+	entry, found, readErr := settings.readDiskEntry(url)
+	require.NoError(t, readErr)
+	require.True(t, found)
+
+	entry.FetchedAt = entry.FetchedAt.Add(-age)
+	require.NoError(t, settings.writeDiskEntry(entry))
+}
+
+func TestLoadFallsBackToRecentCopies(t *testing.T) {
+	server := newFlakyServer(t)
+	dir := t.TempDir()
+	opts := []Option{WithCacheDir(dir), WithCacheKey(cacheKey), WithRetry(RetryPolicy{Attempts: 1})}
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+
+	server.status.Store(http.StatusServiceUnavailable)
+	require.ErrorIs(t, Load(&config, newTestLogger(t), opts...), ErrUnexpectedHTTPStatus)
+
+	var stale testConfig
+
+	require.NoError(t, Load(&stale, newTestLogger(t), append(opts, WithStaleFallback(time.Hour))...))
+	assert.Equal(t, 8080, stale.Service.Port)
+
+	ageCachedCopy(t, dir, server.URL+"/project.toml", 2*time.Hour)
+
+	tooOld := Load(&stale, newTestLogger(t), append(opts, WithStaleFallback(time.Hour))...)
+	require.ErrorIs(t, tooOld, ErrUnexpectedHTTPStatus)
+	assert.ErrorContains(t, tooOld, "old")
+
+	require.NoError(t, Load(&stale, newTestLogger(t), append(opts, WithStaleFallback(0))...), "zero accepts any age")
+}
+
+func TestLoadDoesNotFallBackOnPermanentFailures(t *testing.T) {
+	server := newFlakyServer(t)
+	opts := []Option{
+		WithCacheDir(t.TempDir()), WithCacheKey(cacheKey), WithRetry(RetryPolicy{Attempts: 1}), WithStaleFallback(0),
+	}
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+
+	server.status.Store(http.StatusForbidden)
+	require.ErrorIs(t, Load(&config, newTestLogger(t), opts...), ErrUnexpectedHTTPStatus)
+}
+
+func TestLoadOfflineServesCachedCopies(t *testing.T) {
+	server := newFlakyServer(t)
+	dir := t.TempDir()
+	opts := []Option{WithCacheDir(dir), WithCacheKey(cacheKey), WithEnvironment("prod")}
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+
+	fetched := server.requests.Load()
+	server.status.Store(http.StatusInternalServerError)
+
+	var offline testConfig
+
+	require.NoError(t, Load(&offline, newTestLogger(t), append(opts, WithOffline(true))...))
+	assert.Equal(t, 8080, offline.Service.Port)
+	assert.Equal(t, fetched, server.requests.Load(), "offline loads make no requests")
+
+	var withoutOverlayCopy testConfig
+
+	require.NoError(t, Load(&withoutOverlayCopy, newTestLogger(t), WithCacheDir(dir), WithCacheKey(cacheKey),
+		WithEnvironment("staging"), WithOffline(true)), "optional overlays never fetched count as absent")
+}
+
+func TestLoadOfflineFailsWithoutCachedCopy(t *testing.T) {
+	server := newFlakyServer(t)
+
+	var config testConfig
+
+	loadErr := Load(&config, newTestLogger(t), WithCacheDir(t.TempDir()), WithCacheKey(cacheKey), WithOffline(true))
+	require.ErrorIs(t, loadErr, ErrOffline)
+	assert.Zero(t, server.requests.Load())
+}
@@ -0,0 +1,118 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// kubernetesDataLink is the symlink kubelet swaps atomically when a mounted ConfigMap or
+// Secret is updated; the files in the mount are links through it and see no events of
+// their own.
+const kubernetesDataLink = "..data"
+
+// newFileWatcher creates the fsnotify watcher used for local configuration files.
+func newFileWatcher() (*fsnotify.Watcher, error) {
+	fileWatcher, newWatcherErr := fsnotify.NewWatcher()
+	if newWatcherErr != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", newWatcherErr)
+	}
+
+	return fileWatcher, nil
+}
+
+// affectsFiles reports whether event may have changed the contents of one of files:
+// the file itself was written or created (including renamed into place), or the
+// Kubernetes ..data link in a watched directory was swapped.
+func affectsFiles(event fsnotify.Event, files []string) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+		return false
+	}
+
+	name := filepath.Clean(event.Name)
+	if filepath.Base(name) == kubernetesDataLink {
+		return true
+	}
+
+	return slices.ContainsFunc(files, func(file string) bool {
+		return filepath.Clean(file) == name
+	})
+}
+
+// watchDirectories adds the directory of every configuration file to fileWatcher.
+// Watching directories rather than files keeps notifications flowing when editors
+// replace a file by renaming a temporary copy over it, and catches files that do not
+// exist yet, such as an overlay about to be created. Directories that do not exist are
+// skipped.
+func (w *watcher[T]) watchDirectories(fileWatcher *fsnotify.Watcher) error {
+	for _, file := range w.files {
+		directory := filepath.Dir(file)
+		if slices.Contains(fileWatcher.WatchList(), directory) {
+			continue
+		}
+
+		_, statErr := os.Stat(directory)
+		if errors.Is(statErr, fs.ErrNotExist) {
+			continue
+		}
+
+		addErr := fileWatcher.Add(directory)
+		if addErr != nil {
+			return fmt.Errorf("failed to watch %s: %w", directory, addErr)
+		}
+	}
+
+	return nil
+}
+
+// watchFiles reloads the configuration whenever one of its local files is written or
+// created, or a reload is requested, and publishes changes until ctx is done.
+func (w *watcher[T]) watchFiles(ctx context.Context, fileWatcher *fsnotify.Watcher) {
+	defer func() {
+		closeErr := fileWatcher.Close()
+		if closeErr != nil {
+			w.logger.Error("failed to close file watcher: %v", closeErr)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.reloads:
+		case watchErr, ok := <-fileWatcher.Errors:
+			if !ok {
+				return
+			}
+
+			if !w.send(ctx, Update[T]{Err: fmt.Errorf("file watcher failed: %w", watchErr)}) {
+				return
+			}
+
+			continue
+		case event, ok := <-fileWatcher.Events:
+			if !ok {
+				return
+			}
+
+			if !affectsFiles(event, w.files) {
+				continue
+			}
+		}
+
+		if !w.refresh(ctx, nil) {
+			return
+		}
+
+		watchDirsErr := w.watchDirectories(fileWatcher)
+		if watchDirsErr != nil && !w.send(ctx, Update[T]{Err: watchDirsErr}) {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceFile writes content to a temporary file next to path and renames it over path,
+// as editors and atomic writers do.
+func replaceFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	temporary := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".swp")
+	require.NoError(t, os.WriteFile(temporary, []byte(content), 0o600))
+	require.NoError(t, os.Rename(temporary, path))
+}
+
+func TestWatchReloadsReplacedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[service]\nname = \"first\"\n"), 0o600))
+	t.Setenv("PROJECT_TOML", path)
+
+	updates, _ := startWatch(t)
+	assert.Equal(t, "first", nextUpdate(t, updates).Config.Service.Name)
+
+	replaceFile(t, path, "[service]\nname = \"second\"\n")
+	assert.Equal(t, "second", nextUpdate(t, updates).Config.Service.Name)
+
+	replaceFile(t, path, "[service]\nname = \"third\"\n")
+	assert.Equal(t, "third", nextUpdate(t, updates).Config.Service.Name)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unrelated.toml"), []byte("name = \"other\"\n"), 0o600))
+	noUpdate(t, updates)
+}
+
+func TestWatchReloadsCreatedOverlays(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[service]\nname = \"tts\"\nport = 8080\n"), 0o600))
+	t.Setenv("PROJECT_TOML", path)
+
+	updates, _ := startWatch(t, WithEnvironment("prod"))
+	assert.Equal(t, 8080, nextUpdate(t, updates).Config.Service.Port)
+
+	replaceFile(t, filepath.Join(dir, "project.prod.toml"), "[service]\nport = 443\n")
+
+	update := nextUpdate(t, updates)
+	require.NoError(t, update.Err)
+	assert.Equal(t, 443, update.Config.Service.Port)
+	assert.Equal(t, "tts", update.Config.Service.Name)
+	assert.Equal(t, []string{"service.port"}, update.ChangedKeys)
+}
+
+func TestAffectsFiles(t *testing.T) {
+	t.Parallel()
+
+	files := []string{"/etc/app/project.toml"}
+
+	for name, test := range map[string]struct {
+		event fsnotify.Event
+		want  bool
+	}{
+		"write":             {event: fsnotify.Event{Name: "/etc/app/project.toml", Op: fsnotify.Write}, want: true},
+		"rename into place": {event: fsnotify.Event{Name: "/etc/app/project.toml", Op: fsnotify.Create}, want: true},
+		"other file":        {event: fsnotify.Event{Name: "/etc/app/other.toml", Op: fsnotify.Write}},
+		"chmod":             {event: fsnotify.Event{Name: "/etc/app/project.toml", Op: fsnotify.Chmod}},
+		"removal":           {event: fsnotify.Event{Name: "/etc/app/project.toml", Op: fsnotify.Remove}},
+		"ConfigMap swap":    {event: fsnotify.Event{Name: "/etc/app/" + kubernetesDataLink, Op: fsnotify.Create}, want: true},
+	} {
+		assert.Equal(t, test.want, affectsFiles(test.event, files), name)
+	}
+}
+
+// configMapVersion writes a kubelet-style timestamped directory holding project.toml
+// with content into dir and points the ..data link at it, replacing the link atomically.
+func configMapVersion(t *testing.T, dir, version, content string) {
+	t.Helper()
+
+	versioned := filepath.Join(dir, "..2026_"+version)
+	require.NoError(t, os.Mkdir(versioned, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(versioned, "project.toml"), []byte(content), 0o600))
+
+	staged := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(filepath.Base(versioned), staged))
+	require.NoError(t, os.Rename(staged, filepath.Join(dir, kubernetesDataLink)))
+}
+
+func TestWatchReloadsConfigMapSwaps(t *testing.T) {
+	dir := t.TempDir()
+	configMapVersion(t, dir, "v1", "[service]\nname = \"first\"\n")
+
+	path := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.Symlink(filepath.Join(kubernetesDataLink, "project.toml"), path))
+	t.Setenv("PROJECT_TOML", path)
+
+	updates, _ := startWatch(t)
+	assert.Equal(t, "first", nextUpdate(t, updates).Config.Service.Name)
+
+	configMapVersion(t, dir, "v2", "[service]\nname = \"second\"\n")
+	assert.Equal(t, "second", nextUpdate(t, updates).Config.Service.Name)
+}
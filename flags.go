@@ -0,0 +1,193 @@
+package configurator
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFlagTarget is returned when BindFlags is not given a pointer to a struct.
+var ErrInvalidFlagTarget = errors.New("flag target must be a non-nil pointer to a struct")
+
+var (
+	// durationType is the reflected type of time.Duration, which is bound as a duration
+	// rather than an integer.
+	durationType = reflect.TypeFor[time.Duration]()
+	// stringsType is the reflected type of []string, the only slice type bound as a flag.
+	stringsType = reflect.TypeFor[[]string]()
+)
+
+// BindFlags registers a flag on fs for every supported field of the struct target points
+// to. Parsing fs writes the flag values straight into target, so calling BindFlags after
+// Load and fs.Parse after BindFlags applies command-line flags over the loaded
+// configuration, and the loaded values appear as the flag defaults.
+//
+// A flag is named by the field's flag tag, falling back to its toml tag or lower-cased
+// name; fields of nested structs are prefixed with the parent's name and a dot, as in
+// -nats.url. The usage tag supplies the help text, and flag:"-" skips a field. Strings,
+// booleans, integers, floats, time.Duration, and string slices (comma-separated) are
+// supported; fields of other types are skipped.
+func BindFlags(fs *flag.FlagSet, target any) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrInvalidFlagTarget
+	}
+
+	bindStruct(fs, value.Elem(), "")
+
+	return nil
+}
+
+// bindStruct registers flags for the fields of structValue, prefixing their names.
+func bindStruct(fs *flag.FlagSet, structValue reflect.Value, prefix string) {
+	structType := structValue.Type()
+
+	for index := range structType.NumField() {
+		field := structType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := flagName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := structValue.Field(index)
+
+		if fieldValue.Kind() == reflect.Pointer && fieldValue.Type().Elem().Kind() == reflect.Struct {
+			if fieldValue.IsNil() {
+				continue
+			}
+
+			fieldValue = fieldValue.Elem()
+		}
+
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != durationType {
+			nestedPrefix := prefix
+			if !field.Anonymous {
+				nestedPrefix = prefix + name + "."
+			}
+
+			bindStruct(fs, fieldValue, nestedPrefix)
+
+			continue
+		}
+
+		if !supportsFlag(fieldValue) {
+			continue
+		}
+
+		fs.Var(&fieldFlag{field: fieldValue}, prefix+name, field.Tag.Get("usage"))
+	}
+}
+
+// flagName returns the flag name of field, without any parent prefix.
+func flagName(field reflect.StructField) string {
+	if name := field.Tag.Get("flag"); name != "" {
+		return name
+	}
+
+	if name, _, _ := strings.Cut(field.Tag.Get("toml"), ","); name != "" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
+
+// supportsFlag reports whether fieldFlag can set value.
+func supportsFlag(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return value.Type() == stringsType
+	default:
+		return false
+	}
+}
+
+// fieldFlag is a flag.Value that reads and writes a struct field.
+type fieldFlag struct {
+	field reflect.Value
+}
+
+// String formats the field's current value. The flag package also calls it on a zero
+// fieldFlag, which formats as empty.
+func (f *fieldFlag) String() string {
+	if f == nil || !f.field.IsValid() {
+		return ""
+	}
+
+	if f.field.Type() == durationType {
+		return time.Duration(f.field.Int()).String()
+	}
+
+	if f.field.Kind() == reflect.Slice {
+		return strings.Join(f.field.Interface().([]string), ",")
+	}
+
+	return fmt.Sprint(f.field.Interface())
+}
+
+// Set parses raw as the field's type and stores it.
+func (f *fieldFlag) Set(raw string) error {
+	if f.field.Type() == durationType {
+		duration, parseErr := time.ParseDuration(raw)
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse duration: %w", parseErr)
+		}
+
+		f.field.SetInt(int64(duration))
+
+		return nil
+	}
+
+	switch f.field.Kind() {
+	case reflect.String:
+		f.field.SetString(raw)
+	case reflect.Bool:
+		parsed, parseErr := strconv.ParseBool(raw)
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse bool: %w", parseErr)
+		}
+
+		f.field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, parseErr := strconv.ParseInt(raw, 0, f.field.Type().Bits())
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse integer: %w", parseErr)
+		}
+
+		f.field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, parseErr := strconv.ParseUint(raw, 0, f.field.Type().Bits())
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse unsigned integer: %w", parseErr)
+		}
+
+		f.field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, parseErr := strconv.ParseFloat(raw, f.field.Type().Bits())
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse float: %w", parseErr)
+		}
+
+		f.field.SetFloat(parsed)
+	case reflect.Slice:
+		f.field.Set(reflect.ValueOf(strings.Split(raw, ",")))
+	}
+
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set with a bare -name.
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.IsValid() && f.field.Kind() == reflect.Bool
+}
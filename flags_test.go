@@ -0,0 +1,132 @@
+package configurator
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RegionConfig is embedded in flagConfig, so its fields are bound without a prefix. It is
+// exported because BindFlags skips unexported embedded structs like other unexported
+// fields.
+type RegionConfig struct {
+	Region string `toml:"region"`
+}
+
+// flagConfig has a field of every kind BindFlags names or binds.
+type flagConfig struct {
+	RegionConfig
+
+	Name     string            `toml:"name,omitempty" usage:"service name"`
+	Port     int               `flag:"listen-port"`
+	Verbose  bool              `toml:"verbose"`
+	Ratio    float64           `toml:"ratio"`
+	Workers  uint8             `toml:"workers"`
+	Timeout  time.Duration     `toml:"timeout"`
+	Hosts    []string          `toml:"hosts"`
+	Secret   string            `flag:"-"          toml:"secret"`
+	Labels   map[string]string `toml:"labels"`
+	Untagged string
+	NATS     struct {
+		URL string `toml:"url"`
+		TLS struct {
+			Cert string `toml:"cert"`
+		} `toml:"tls"`
+	} `toml:"nats"`
+	Optional *struct {
+		Value string `toml:"value"`
+	} `toml:"optional"`
+	Present *struct {
+		Value string `toml:"value"`
+	} `toml:"present"`
+}
+
+// boundFlags binds config to a new flag set and returns the set and the flag names.
+func boundFlags(t *testing.T, config *flagConfig) (*flag.FlagSet, []string) {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	require.NoError(t, BindFlags(fs, config))
+
+	var names []string
+
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+
+	return fs, names
+}
+
+func TestBindFlagsNamesFlags(t *testing.T) {
+	t.Parallel()
+
+	config := &flagConfig{Present: &struct {
+		Value string `toml:"value"`
+	}{}}
+
+	fs, names := boundFlags(t, config)
+	assert.ElementsMatch(t, []string{
+		"region", "name", "listen-port", "verbose", "ratio", "workers", "timeout", "hosts",
+		"untagged", "nats.url", "nats.tls.cert", "present.value",
+	}, names)
+	assert.Equal(t, "service name", fs.Lookup("name").Usage)
+}
+
+func TestBindFlagsParsesOverLoadedValues(t *testing.T) {
+	t.Parallel()
+
+	config := &flagConfig{Name: "tts", Port: 8080, Timeout: time.Second, Hosts: []string{"a"}}
+	config.NATS.URL = "nats://localhost:4222"
+
+	fs, _ := boundFlags(t, config)
+	assert.Equal(t, "8080", fs.Lookup("listen-port").DefValue)
+	assert.Equal(t, "1s", fs.Lookup("timeout").DefValue)
+	assert.Equal(t, "a", fs.Lookup("hosts").DefValue)
+
+	require.NoError(t, fs.Parse([]string{
+		"-listen-port", "0x1F90", "-verbose", "-ratio", "0.25", "-workers", "4", "-timeout", "1m30s",
+		"-hosts", "b,c", "-nats.tls.cert", "/run/cert.pem", "-region", "eu",
+	}))
+	assert.Equal(t, "tts", config.Name)
+	assert.Equal(t, 8080, config.Port)
+	assert.True(t, config.Verbose)
+	assert.InDelta(t, 0.25, config.Ratio, 0)
+	assert.Equal(t, uint8(4), config.Workers)
+	assert.Equal(t, 90*time.Second, config.Timeout)
+	assert.Equal(t, []string{"b", "c"}, config.Hosts)
+	assert.Equal(t, "nats://localhost:4222", config.NATS.URL)
+	assert.Equal(t, "/run/cert.pem", config.NATS.TLS.Cert)
+	assert.Equal(t, "eu", config.Region)
+}
+
+func TestBindFlagsRejectsInvalidValues(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"-listen-port", "many"},
+		{"-workers", "300"},
+		{"-verbose=maybe"},
+		{"-timeout", "soon"},
+		{"-ratio", "half"},
+	} {
+		fs, _ := boundFlags(t, &flagConfig{})
+		require.Error(t, fs.Parse(args), args)
+	}
+}
+
+func TestBindFlagsRejectsInvalidTargets(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	var config *flagConfig
+
+	require.ErrorIs(t, BindFlags(fs, flagConfig{}), ErrInvalidFlagTarget)
+	require.ErrorIs(t, BindFlags(fs, config), ErrInvalidFlagTarget)
+	require.ErrorIs(t, BindFlags(fs, new(string)), ErrInvalidFlagTarget)
+}
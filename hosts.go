@@ -0,0 +1,107 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AllowedHostsVariable names the environment variable holding a comma-separated host
+	// allowlist for remote configuration, used when WithAllowedHosts is not given.
+	AllowedHostsVariable = "PROJECT_TOML_ALLOWED_HOSTS"
+	// RequireHTTPSVariable names the environment variable that, when true, refuses plain
+	// HTTP configuration URLs, used when WithRequireHTTPS is not given.
+	RequireHTTPSVariable = "PROJECT_TOML_REQUIRE_HTTPS"
+	// httpsScheme is the only scheme accepted when HTTPS is required.
+	httpsScheme = "https"
+	// wildcardPrefix marks an allowlist entry that matches any subdomain.
+	wildcardPrefix = "*."
+)
+
+var (
+	// ErrHostNotAllowed is returned when a configuration URL names a host outside the allowlist.
+	ErrHostNotAllowed = errors.New("configuration host not allowed")
+	// ErrInsecureURL is returned when HTTPS is required and a configuration URL is not HTTPS.
+	ErrInsecureURL = errors.New("configuration URL must use HTTPS")
+)
+
+// WithAllowedHosts restricts remote configuration, including redirects, to the given
+// hosts. An entry of the form *.example.com matches any subdomain of example.com. No
+// hosts means no restriction.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(o *options) {
+		o.allowedHosts = hosts
+	}
+}
+
+// WithRequireHTTPS refuses remote configuration, including redirects, that is not
+// fetched over HTTPS.
+func WithRequireHTTPS(require bool) Option {
+	return func(o *options) {
+		o.requireHTTPS = require
+	}
+}
+
+// defaultAllowedHosts returns the allowlist from AllowedHostsVariable.
+func defaultAllowedHosts() []string {
+	return hostList(os.Getenv(AllowedHostsVariable))
+}
+
+// hostList splits a comma-separated list of hosts, dropping empty entries.
+func hostList(value string) []string {
+	var hosts []string
+
+	for host := range strings.SplitSeq(value, ",") {
+		if trimmed := strings.TrimSpace(host); trimmed != "" {
+			hosts = append(hosts, trimmed)
+		}
+	}
+
+	return hosts
+}
+
+// defaultRequireHTTPS reports whether RequireHTTPSVariable is set to true.
+func defaultRequireHTTPS() bool {
+	require, _ := strconv.ParseBool(os.Getenv(RequireHTTPSVariable))
+
+	return require
+}
+
+// checkURL enforces the host allowlist and HTTPS requirement on a remote URL.
+func checkURL(target *url.URL, settings *options) error {
+	if settings.requireHTTPS && target.Scheme != httpsScheme {
+		return fmt.Errorf("%w: %s", ErrInsecureURL, target.Redacted())
+	}
+
+	if len(settings.allowedHosts) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(target.Hostname())
+	if !matchesHost(host, settings.allowedHosts) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+
+	return nil
+}
+
+// matchesHost reports whether host matches an entry of hosts. An entry of the form
+// *.example.com matches any subdomain of example.com.
+func matchesHost(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+
+	return slices.ContainsFunc(hosts, func(entry string) bool {
+		entry = strings.ToLower(entry)
+
+		if suffix, isWildcard := strings.CutPrefix(entry, wildcardPrefix); isWildcard {
+			return strings.HasSuffix(host, "."+suffix)
+		}
+
+		return host == entry
+	})
+}
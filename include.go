@@ -0,0 +1,200 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// includeKey is the top-level key listing the files a document includes.
+	includeKey = "include"
+	// DefaultMaxIncludeDepth limits how deeply include directives may nest.
+	DefaultMaxIncludeDepth = 8
+)
+
+var (
+	// ErrIncludeCycle is returned when documents include each other in a cycle.
+	ErrIncludeCycle = errors.New("include cycle detected")
+	// ErrIncludeTooDeep is returned when include directives nest deeper than allowed.
+	ErrIncludeTooDeep = errors.New("include depth limit exceeded")
+	// ErrInvalidInclude is returned when the include key is not a list of strings.
+	ErrInvalidInclude = errors.New("include must be a list of strings")
+	// ErrLocalInclude is returned when a remote document includes a local file.
+	ErrLocalInclude = errors.New("remote document cannot include local file")
+)
+
+// WithMaxIncludeDepth sets how deeply include directives may nest.
+func WithMaxIncludeDepth(depth int) Option {
+	return func(o *options) {
+		o.maxIncludeDepth = depth
+	}
+}
+
+// resolveIncludes merges the documents listed in table's include directive, in order,
+// and then table itself over them, returning the result without the directive.
+// Entries are resolved relative to source and may be local files or URLs, though a
+// remote document may only include other remote documents; they are
+// fetched in parallel, at most WithMaxParallelFetches at a time across the whole load,
+// and every failing entry is reported as a SourceError. chain holds the sources
+// currently being included, outermost first, and is used to detect cycles.
+func (a *assembly) resolveIncludes(table map[string]any, source string, chain []string) (map[string]any, error) {
+	rawIncludes, hasIncludes := table[includeKey]
+	if !hasIncludes {
+		return table, nil
+	}
+
+	includes, includesErr := includeList(rawIncludes)
+	if includesErr != nil {
+		return nil, fmt.Errorf("%s: %w", source, includesErr)
+	}
+
+	chain = append(chain, source)
+	if len(chain) > a.settings.maxIncludeDepth {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeTooDeep, strings.Join(chain, " -> "))
+	}
+
+	included := make([]map[string]any, len(includes))
+	includeErrs := make([]error, len(includes))
+
+	var wg sync.WaitGroup
+
+	for index, include := range includes {
+		wg.Go(func() {
+			resolved, resolveErr := resolveReference(source, include)
+			if resolveErr != nil {
+				includeErrs[index] = &SourceError{Source: include, Err: resolveErr}
+
+				return
+			}
+
+			included[index], includeErrs[index] = a.readInclude(resolved, chain)
+		})
+	}
+
+	wg.Wait()
+
+	includeErr := errors.Join(includeErrs...)
+	if includeErr != nil {
+		return nil, includeErr
+	}
+
+	merged := make(map[string]any)
+	for _, includedTable := range included {
+		merged = Merge(merged, includedTable, a.settings.merge)
+	}
+
+	a.markModified()
+
+	return Merge(merged, withoutKey(table, includeKey), a.settings.merge), nil
+}
+
+// readInclude reads the included document at source and resolves its own includes.
+func (a *assembly) readInclude(source string, chain []string) (map[string]any, error) {
+	if slices.Contains(chain, source) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrIncludeCycle, strings.Join(chain, " -> "), source)
+	}
+
+	a.addFile(source)
+
+	var (
+		content []byte
+		format  string
+		readErr error
+	)
+
+	a.limiter.do(func() {
+		content, format, readErr = readSource(source, a.settings, a.cache, a.logger)
+	})
+
+	if readErr != nil {
+		return nil, &SourceError{Source: source, Err: fmt.Errorf("failed to read include: %w", readErr)}
+	}
+
+	content, admitErr := a.admit(source, content, format)
+	if admitErr != nil {
+		return nil, &SourceError{Source: source, Err: admitErr}
+	}
+
+	table, parseErr := parseTable(content, a.settings)
+	if parseErr != nil {
+		return nil, &SourceError{Source: source, Err: fmt.Errorf("failed to parse include: %w", parseErr)}
+	}
+
+	referenceErr := checkReferences(a.settings, source, table)
+	if referenceErr != nil {
+		return nil, &SourceError{Source: source, Err: referenceErr}
+	}
+
+	return a.resolveIncludes(table, source, chain)
+}
+
+// includeList converts the raw include directive into a list of references.
+func includeList(raw any) ([]string, error) {
+	values, isList := raw.([]any)
+	if !isList {
+		return nil, ErrInvalidInclude
+	}
+
+	includes := make([]string, 0, len(values))
+
+	for _, value := range values {
+		include, isString := value.(string)
+		if !isString {
+			return nil, ErrInvalidInclude
+		}
+
+		includes = append(includes, include)
+	}
+
+	return includes, nil
+}
+
+// resolveReference resolves ref relative to the document at base. References from a
+// local document to absolute paths and URLs are returned unchanged. References from a
+// remote document are resolved with URL semantics, so /shared/nats.toml names a path on
+// the same host, and must not name a local file.
+func resolveReference(base, ref string) (string, error) {
+	if basePath, baseIsLocal := localPath(base); baseIsLocal {
+		refPath, refIsLocal := localPath(ref)
+		if !refIsLocal || filepath.IsAbs(refPath) {
+			return ref, nil
+		}
+
+		return filepath.Join(filepath.Dir(basePath), refPath), nil
+	}
+
+	refURL, refErr := url.Parse(ref)
+	if refErr != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidInclude, ref)
+	}
+
+	if refURL.IsAbs() {
+		if refURL.Scheme == fileScheme {
+			return "", fmt.Errorf("%w: %s", ErrLocalInclude, ref)
+		}
+
+		return ref, nil
+	}
+
+	requestURL, socket, isSocket, socketErr := splitSocketURL(base)
+	if socketErr != nil {
+		return "", socketErr
+	}
+
+	baseURL, baseErr := url.Parse(requestURL)
+	if baseErr != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidInclude, ref)
+	}
+
+	resolved := baseURL.ResolveReference(refURL)
+	if isSocket {
+		resolved = &url.URL{Scheme: unixScheme, Path: socket + ":" + resolved.Path, RawQuery: resolved.RawQuery, Fragment: resolved.Fragment}
+	}
+
+	return resolved.String(), nil
+}
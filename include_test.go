@@ -0,0 +1,57 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveReference(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base, ref, want string
+	}{
+		{"/srv/app/project.toml", "nats.toml", "/srv/app/nats.toml"},
+		{"/srv/app/project.toml", "../shared/nats.toml", "/srv/shared/nats.toml"},
+		{"/srv/app/project.toml", "/etc/nats.toml", "/etc/nats.toml"},
+		{"/srv/app/project.toml", "https://cfg.example/nats.toml", "https://cfg.example/nats.toml"},
+		{"https://cfg.example/app/project.toml", "nats.toml", "https://cfg.example/app/nats.toml"},
+		{"https://cfg.example/app/project.toml", "../nats.toml", "https://cfg.example/nats.toml"},
+		{"https://cfg.example/app/project.toml", "/shared/nats.toml", "https://cfg.example/shared/nats.toml"},
+		{"https://cfg.example/app/project.toml", "https://other.example/nats.toml", "https://other.example/nats.toml"},
+	}
+
+	for _, test := range tests {
+		resolved, resolveErr := resolveReference(test.base, test.ref)
+		require.NoError(t, resolveErr, test.ref)
+		assert.Equal(t, test.want, resolved, test.ref)
+	}
+
+	_, localErr := resolveReference("https://cfg.example/app/project.toml", "file:///etc/passwd")
+	require.ErrorIs(t, localErr, ErrLocalInclude)
+}
+
+func TestLoadNeverReadsLocalFilesForRemoteDocuments(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret.toml")
+	require.NoError(t, os.WriteFile(secret, []byte("[service]\nname = \"leaked\"\n"), 0o600))
+
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "include = [\"" + secret + "\"]\n",
+		secret:          "[service]\nname = \"remote\"\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, "remote", config.Service.Name)
+
+	server = serveDocuments(t, map[string]string{"/project.toml": "include = [\"file://" + secret + "\"]\n"})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrLocalInclude)
+}
@@ -0,0 +1,119 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errUnknownKMSKey is returned by fakeKMS for key IDs it does not hold.
+var errUnknownKMSKey = errors.New("unknown key")
+
+// fakeKMS wraps data keys by XOR with a per-key pad, which is enough to tell keys apart.
+type fakeKMS struct {
+	pads     map[string]byte
+	decrypts atomic.Int32
+}
+
+// newFakeKMS returns a KMS holding the named keys.
+func newFakeKMS(keyIDs ...string) *fakeKMS {
+	pads := make(map[string]byte, len(keyIDs))
+	for index, keyID := range keyIDs {
+		pads[keyID] = byte(index + 1)
+	}
+
+	return &fakeKMS{pads: pads}
+}
+
+// Encrypt wraps plaintext under keyID.
+func (k *fakeKMS) Encrypt(_ context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	return k.xor(keyID, plaintext)
+}
+
+// Decrypt unwraps ciphertext under keyID.
+func (k *fakeKMS) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	k.decrypts.Add(1)
+
+	return k.xor(keyID, ciphertext)
+}
+
+// xor applies the pad of keyID to data.
+func (k *fakeKMS) xor(keyID string, data []byte) ([]byte, error) {
+	pad, found := k.pads[keyID]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", errUnknownKMSKey, keyID)
+	}
+
+	result := bytes.Clone(data)
+	for index := range result {
+		result[index] ^= pad
+	}
+
+	return result, nil
+}
+
+func TestLoadDecryptsKMSValues(t *testing.T) {
+	kms := newFakeKMS("alias/config")
+
+	password, encryptErr := EncryptValueKMS(context.Background(), kms, "alias/config", "hunter2")
+	require.NoError(t, encryptErr)
+	require.True(t, strings.HasPrefix(password, kmsValuePrefix))
+
+	localDocument(t, fmt.Sprintf("password = %q\nplain = %q\n", password, password))
+
+	var config secretConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithKMS(kms, "alias/config")))
+	assert.Equal(t, "hunter2", config.Password)
+	assert.Equal(t, password, config.Plain)
+	assert.Equal(t, int32(1), kms.decrypts.Load())
+}
+
+func TestLoadRejectsUndecryptableKMSValues(t *testing.T) {
+	kms := newFakeKMS("alias/config", "alias/other")
+
+	password, encryptErr := EncryptValueKMS(context.Background(), kms, "alias/config", "hunter2")
+	require.NoError(t, encryptErr)
+
+	parts := strings.SplitN(strings.TrimPrefix(password, kmsValuePrefix), ":", kmsValueParts)
+	sealed, decodeErr := base64.StdEncoding.DecodeString(parts[1])
+	require.NoError(t, decodeErr)
+
+	sealed[len(sealed)-1] ^= 1
+	tampered := kmsValuePrefix + parts[0] + ":" + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := map[string]struct {
+		value   string
+		options []Option
+		want    error
+	}{
+		"without WithKMS":     {value: password, want: ErrKMSNotConfigured},
+		"tampered ciphertext": {value: tampered, options: []Option{WithKMS(kms, "alias/config")}, want: ErrInvalidSecret},
+		"wrong KMS key":       {value: password, options: []Option{WithKMS(kms, "alias/other")}, want: ErrInvalidSecret},
+		"unknown KMS key":     {value: password, options: []Option{WithKMS(kms, "alias/gone")}, want: errUnknownKMSKey},
+		"missing data key":    {value: "kms:sealed-only", options: []Option{WithKMS(kms, "alias/config")}, want: ErrInvalidSecret},
+		"malformed data key": {
+			value: kmsValuePrefix + "not base64!:" + parts[1], options: []Option{WithKMS(kms, "alias/config")}, want: ErrInvalidSecret,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			localDocument(t, fmt.Sprintf("password = %q\n", test.value))
+
+			var config secretConfig
+
+			loadErr := Load(&config, newTestLogger(t), test.options...)
+			require.ErrorIs(t, loadErr, test.want)
+			assert.ErrorContains(t, loadErr, "Password")
+		})
+	}
+}
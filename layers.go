@@ -0,0 +1,348 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/book-expert/logger"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// EnvironmentVariable names the environment variable that selects the environment
+// overlay, e.g. APP_ENV=prod merges project.prod.toml over project.toml.
+const EnvironmentVariable = "APP_ENV"
+
+// WithEnvironment selects the environment overlay explicitly instead of reading it
+// from EnvironmentVariable.
+func WithEnvironment(name string) Option {
+	return func(o *options) {
+		o.environment = name
+	}
+}
+
+// document is the effective configuration assembled from the base document and its
+// layers, together with the local files it was read from, the digests of the layers and
+// includes merged into it by source, and whether the base document itself is a local
+// file.
+type document struct {
+	content   []byte
+	files     []string
+	layers    map[string]string
+	baseLocal bool
+}
+
+// assembly is the in-progress effective configuration passed through the layering steps.
+// Includes are read concurrently, so files, digests, and modified are guarded by mu.
+type assembly struct {
+	source   string
+	layers   map[Layer]map[string]any
+	settings *options
+	cache    *fetchCache
+	logger   *logger.Logger
+	limiter  fetchLimiter
+
+	mu       sync.Mutex
+	files    []string
+	digests  map[string]string
+	modified bool
+}
+
+// assemblyStep contributes layers to the effective configuration; steps run in order.
+type assemblyStep func(*assembly) error
+
+// assemblySteps lists the layering steps applied to every base document.
+func assemblySteps() []assemblyStep {
+	return []assemblyStep{
+		collectDefaults,
+		collectMachineConfig,
+		collectEnvironmentOverlay,
+		collectLocalOverride,
+		collectProfile,
+		applyConditions,
+	}
+}
+
+// assemble resolves the include directives of the base document read from source,
+// collects the other layers, merges them in precedence order, expands templates,
+// applies environment overrides, and resolves secret references.
+// When nothing but the base document contributes, its content is returned unchanged.
+func assemble(base []byte, source string, settings *options, cache *fetchCache, logger *logger.Logger) (*document, error) {
+	state, collectErr := collectLayers(base, source, settings, cache, logger)
+	if collectErr != nil {
+		return nil, collectErr
+	}
+
+	_, baseLocal := localPath(source)
+
+	composed, modified := state.compose()
+
+	effective, usesTemplates, templateErr := resolveTemplates(composed, settings.merge)
+	if templateErr != nil {
+		return nil, templateErr
+	}
+
+	effective, usesEnvPrefixes, envErr := applyEnvPrefixes(effective)
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	effective, usesSecrets, secretErr := resolveReferences(effective, newSecretReferences(settings, logger).resolve, settings.maxParallelFetches)
+	if secretErr != nil {
+		return nil, secretErr
+	}
+
+	if !modified && !usesTemplates && !usesEnvPrefixes && !usesSecrets {
+		return &document{content: base, files: state.files, layers: state.digests, baseLocal: baseLocal}, nil
+	}
+
+	content, marshalErr := toml.Marshal(effective)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to encode effective configuration: %w", marshalErr)
+	}
+
+	return &document{content: content, files: state.files, layers: state.digests, baseLocal: baseLocal}, nil
+}
+
+// collectLayers resolves the include directives of the base document read from source
+// and runs the layering steps, returning the assembly holding every layer.
+func collectLayers(base []byte, source string, settings *options, cache *fetchCache, logger *logger.Logger) (*assembly, error) {
+	table, decrypted, parseErr := decodeTable(base, settings)
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, parseErr)
+	}
+
+	referenceErr := checkReferences(settings, source, table)
+	if referenceErr != nil {
+		return nil, referenceErr
+	}
+
+	state := &assembly{
+		source:   source,
+		layers:   make(map[Layer]map[string]any),
+		settings: settings,
+		cache:    cache,
+		logger:   logger,
+		limiter:  newFetchLimiter(settings.maxParallelFetches),
+		digests:  make(map[string]string),
+		modified: decrypted,
+	}
+
+	state.addFile(source)
+
+	resolved, includeErr := state.resolveIncludes(table, source, nil)
+	if includeErr != nil {
+		return nil, includeErr
+	}
+
+	state.layers[LayerBase] = resolved
+
+	for _, step := range assemblySteps() {
+		stepErr := step(state)
+		if stepErr != nil {
+			return nil, stepErr
+		}
+	}
+
+	return state, nil
+}
+
+// compose merges the collected layers from lowest to highest precedence, which removes
+// every UnsetMarker key. It reports whether the result may differ from the base document
+// as read.
+func (a *assembly) compose() (map[string]any, bool) {
+	effective := make(map[string]any)
+	modified := a.modified || len(a.layers) > 1 || containsUnset(a.layers[LayerBase])
+
+	for _, layer := range a.settings.precedence {
+		table, found := a.layers[layer]
+		if found {
+			effective = Merge(effective, table, a.settings.merge)
+		}
+	}
+
+	if !slices.Contains(a.settings.precedence, LayerBase) {
+		modified = true
+	}
+
+	return effective, modified
+}
+
+// setLayer records the table contributed by layer.
+func (a *assembly) setLayer(layer Layer, table map[string]any) {
+	a.layers[layer] = table
+}
+
+// markModified records that the effective configuration differs from the base document.
+func (a *assembly) markModified() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.modified = true
+}
+
+// addFile records source for watching when it is a local file.
+func (a *assembly) addFile(source string) {
+	unpinned, _ := splitPin(source)
+
+	path, isLocal := localPath(unpinned)
+	if !isLocal {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.files = append(a.files, path)
+}
+
+// admit verifies a layer or include read from source against the signatures settings
+// require, records its digest for the lock file, and converts it to TOML.
+func (a *assembly) admit(source string, content []byte, format string) ([]byte, error) {
+	signatureErr := verifySignatures(a.settings, source, content, a.cache, a.logger)
+	if signatureErr != nil {
+		return nil, signatureErr
+	}
+
+	a.mu.Lock()
+	a.digests[source] = contentHash(content)
+	a.mu.Unlock()
+
+	return convertDocument(content, format)
+}
+
+// readLayer reads and parses the optional layer at source. Local layer files are
+// recorded for watching even when they do not exist yet. The boolean result is false
+// when the layer does not exist.
+func (a *assembly) readLayer(source string) (map[string]any, bool, error) {
+	a.addFile(source)
+
+	content, format, readErr := readSource(source, a.settings, a.cache, a.logger)
+	if errors.Is(readErr, ErrSourceNotFound) {
+		return nil, false, nil
+	}
+
+	if readErr != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", source, readErr)
+	}
+
+	content, admitErr := a.admit(source, content, format)
+	if admitErr != nil {
+		return nil, false, fmt.Errorf("%s: %w", source, admitErr)
+	}
+
+	table, parseErr := parseTable(content, a.settings)
+	if parseErr != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", source, parseErr)
+	}
+
+	referenceErr := checkReferences(a.settings, source, table)
+	if referenceErr != nil {
+		return nil, false, referenceErr
+	}
+
+	resolved, includeErr := a.resolveIncludes(table, source, nil)
+	if includeErr != nil {
+		return nil, false, includeErr
+	}
+
+	return resolved, true, nil
+}
+
+// selectedEnvironment returns the environment chosen with WithEnvironment or, failing
+// that, EnvironmentVariable.
+func selectedEnvironment(settings *options) string {
+	if settings.environment != "" {
+		return settings.environment
+	}
+
+	return os.Getenv(EnvironmentVariable)
+}
+
+// collectEnvironmentOverlay reads the overlay for the selected environment, e.g.
+// project.prod.toml for APP_ENV=prod, as the LayerEnvironment layer.
+func collectEnvironmentOverlay(state *assembly) error {
+	environment := selectedEnvironment(state.settings)
+	if environment == "" {
+		return nil
+	}
+
+	overlay, found, overlayErr := state.readLayer(withNameSuffix(state.source, environment))
+	if overlayErr != nil {
+		return fmt.Errorf("failed to load %s overlay: %w", environment, overlayErr)
+	}
+
+	if found {
+		state.setLayer(LayerEnvironment, overlay)
+	}
+
+	return nil
+}
+
+// parseTable parses a configuration document into a generic table, decrypting it as
+// described for decodeTable.
+func parseTable(content []byte, settings *options) (map[string]any, error) {
+	table, _, decodeErr := decodeTable(content, settings)
+
+	return table, decodeErr
+}
+
+// decodeTable parses a TOML document, an age-encrypted one, or a SOPS-encrypted JSON or
+// YAML document into a generic table, and decrypts its age: values. The boolean result
+// reports whether anything was decrypted, in which case the table differs from content.
+func decodeTable(content []byte, settings *options) (map[string]any, bool, error) {
+	if format, encrypted := sopsFormat(content); encrypted {
+		table, decryptErr := decryptSOPS(content, format, settings)
+
+		return table, true, decryptErr
+	}
+
+	if isAgeEncrypted(content) {
+		plaintext, decryptErr := decryptAgeDocument(content)
+		if decryptErr != nil {
+			return nil, false, decryptErr
+		}
+
+		table, _, decodeErr := decodeTable(plaintext, settings)
+
+		return table, true, decodeErr
+	}
+
+	table := make(map[string]any)
+
+	unmarshalErr := toml.Unmarshal(content, &table)
+	if unmarshalErr != nil {
+		return nil, false, fmt.Errorf("failed to parse TOML: %w", unmarshalErr)
+	}
+
+	return decryptAgeValues(table)
+}
+
+// withNameSuffix inserts suffix before the extension of the file named by source,
+// turning project.toml into project.<suffix>.toml for paths and URLs alike.
+func withNameSuffix(source, suffix string) string {
+	if _, isLocal := localPath(source); isLocal {
+		return insertSuffix(source, suffix)
+	}
+
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil {
+		return insertSuffix(source, suffix)
+	}
+
+	parsed.Path = insertSuffix(parsed.Path, suffix)
+
+	return parsed.String()
+}
+
+// insertSuffix inserts "."+suffix before the extension of name.
+func insertSuffix(name, suffix string) string {
+	extension := filepath.Ext(name)
+
+	return strings.TrimSuffix(name, extension) + "." + suffix + extension
+}
@@ -0,0 +1,40 @@
+package configurator
+
+import "fmt"
+
+// localOverrideSuffix names the untracked override file, e.g. project.local.toml.
+const localOverrideSuffix = "local"
+
+// WithLocalOverride enables or disables merging the untracked project.local.toml override
+// next to a local configuration file. It is disabled by default, so a stray file next to
+// the configuration does not change it unless the service opts in.
+func WithLocalOverride(enabled bool) Option {
+	return func(o *options) {
+		o.localOverride = enabled
+	}
+}
+
+// collectLocalOverride reads the machine-specific override file (project.local.toml for
+// project.toml) as the LayerLocal layer. The override is intended to be gitignored and
+// only applies to local configuration files.
+func collectLocalOverride(state *assembly) error {
+	if !state.settings.localOverride {
+		return nil
+	}
+
+	path, isLocal := localPath(state.source)
+	if !isLocal {
+		return nil
+	}
+
+	override, found, overrideErr := state.readLayer(insertSuffix(path, localOverrideSuffix))
+	if overrideErr != nil {
+		return fmt.Errorf("failed to load local override: %w", overrideErr)
+	}
+
+	if found {
+		state.setLayer(LayerLocal, override)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMergesLocalOverrideOnlyWhenEnabled(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.toml"), []byte("[service]\nname = \"tts\"\nport = 8080\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.local.toml"), []byte("[service]\nport = 9999\n"), 0o600))
+	t.Setenv("PROJECT_TOML", filepath.Join(dir, "project.toml"))
+
+	var disabled testConfig
+
+	require.NoError(t, Load(&disabled, newTestLogger(t)))
+	assert.Equal(t, 8080, disabled.Service.Port)
+
+	var enabled testConfig
+
+	require.NoError(t, Load(&enabled, newTestLogger(t), WithLocalOverride(true)))
+	assert.Equal(t, 9999, enabled.Service.Port)
+	assert.Equal(t, "tts", enabled.Service.Name)
+}
+
+func TestLoadIgnoresLocalOverrideOfRemoteDocuments(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml":       "[service]\nport = 8080\n",
+		"/project.local.toml": "[service]\nport = 9999\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithLocalOverride(true)))
+	assert.Equal(t, 8080, config.Service.Port)
+}
+
+func TestLoadWithoutLocalOverrideFile(t *testing.T) {
+	localDocument(t, "[service]\nport = 8080\n")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithLocalOverride(true)))
+	assert.Equal(t, 8080, config.Service.Port)
+}
@@ -0,0 +1,156 @@
+package configurator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// lockFilePermissions is the file mode used when writing a lock file.
+const lockFilePermissions = 0o644
+
+// ErrLockMismatch is returned when the fetched configuration does not match the hash recorded in the lock file.
+var ErrLockMismatch = errors.New("configuration does not match lock file")
+
+// Lock records the configuration that was reviewed for a deployment. SHA256 is the
+// digest of the base document and Layers that of every include and overlay merged into
+// it, by source. Format names the format a vendored copy was served in when it is JSON
+// or YAML rather than TOML, and Sealed marks a vendored copy encrypted with the cache
+// key.
+type Lock struct {
+	Source    string            `toml:"source"`
+	SHA256    string            `toml:"sha256"`
+	FetchedAt time.Time         `toml:"fetched_at"`
+	Format    string            `toml:"format,omitempty"`
+	Sealed    bool              `toml:"sealed,omitempty"`
+	Layers    map[string]string `toml:"layers,omitempty"`
+}
+
+// WithLockFile makes Load verify the fetched configuration, including its includes and
+// overlays, against the lock file at path and fail with ErrLockMismatch if any document
+// drifted or was added or removed.
+func WithLockFile(path string) Option {
+	return func(o *options) {
+		o.lockFile = path
+	}
+}
+
+// WriteLockFile fetches the configuration referenced by PROJECT_TOML and records its
+// source URL and content hash, and the hashes of its includes and overlays, in the lock
+// file at path (conventionally project.toml.lock). Options customize the fetch as they
+// do for Load.
+func WriteLockFile(path string, logger *logger.Logger, opts ...Option) error {
+	settings := newOptions(opts)
+	settings.lockFile, settings.vendoredCopy = "", ""
+
+	tomlContent, source, format, readErr := readContent(settings, nil, logger)
+	if readErr != nil {
+		return readErr
+	}
+
+	converted, convertErr := convertDocument(tomlContent, format)
+	if convertErr != nil {
+		return fmt.Errorf("%s: %w", source, convertErr)
+	}
+
+	state, collectErr := collectLayers(converted, source, settings, nil, logger)
+	if collectErr != nil {
+		return collectErr
+	}
+
+	lock := Lock{
+		Source:    os.Getenv("PROJECT_TOML"),
+		SHA256:    contentHash(tomlContent),
+		FetchedAt: time.Now().UTC(),
+		Layers:    state.digests,
+	}
+
+	data, marshalErr := toml.Marshal(lock)
+	if marshalErr != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", marshalErr)
+	}
+
+	writeErr := os.WriteFile(path, data, lockFilePermissions)
+	if writeErr != nil {
+		return fmt.Errorf("failed to write lock file %s: %w", path, writeErr)
+	}
+
+	return nil
+}
+
+// verifyLock compares the content hash against the lock file at path.
+func verifyLock(path string, content []byte) error {
+	lock, lockErr := readLock(path)
+	if lockErr != nil {
+		return lockErr
+	}
+
+	actual := contentHash(content)
+	if actual != lock.SHA256 {
+		return fmt.Errorf("%w: expected sha256 %s from %s, got %s", ErrLockMismatch, lock.SHA256, lock.Source, actual)
+	}
+
+	return nil
+}
+
+// verifyLockedLayers compares the digests of the includes and overlays merged into the
+// configuration against those recorded in the lock file at path.
+func verifyLockedLayers(path string, layers map[string]string) error {
+	lock, lockErr := readLock(path)
+	if lockErr != nil {
+		return lockErr
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(layers)) {
+		digest := layers[source]
+
+		locked, found := lock.Layers[source]
+		if !found {
+			return fmt.Errorf("%w: %s is not in %s", ErrLockMismatch, source, path)
+		}
+
+		if locked != digest {
+			return fmt.Errorf("%w: expected sha256 %s from %s, got %s", ErrLockMismatch, locked, source, digest)
+		}
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(lock.Layers)) {
+		if _, found := layers[source]; !found {
+			return fmt.Errorf("%w: %s is no longer merged", ErrLockMismatch, source)
+		}
+	}
+
+	return nil
+}
+
+// readLock reads the lock file at path.
+func readLock(path string) (Lock, error) {
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return Lock{}, fmt.Errorf("failed to read lock file %s: %w", path, readErr)
+	}
+
+	var lock Lock
+
+	unmarshalErr := toml.Unmarshal(data, &lock)
+	if unmarshalErr != nil {
+		return Lock{}, fmt.Errorf("failed to parse lock file %s: %w", path, unmarshalErr)
+	}
+
+	return lock, nil
+}
+
+// contentHash returns the hex-encoded SHA-256 digest of content.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,142 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// documentServer serves documents by path and lets tests replace or remove them.
+type documentServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	documents map[string]string
+}
+
+// newDocumentServer starts a documentServer and points PROJECT_TOML at its project.toml.
+func newDocumentServer(t *testing.T, documents map[string]string) *documentServer {
+	t.Helper()
+
+	server := &documentServer{documents: documents}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		content, found := server.documents[r.URL.Path]
+		server.mu.Unlock()
+
+		if !found {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	return server
+}
+
+// set serves content at path, or nothing when content is empty.
+func (s *documentServer) set(path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if content == "" {
+		delete(s.documents, path)
+
+		return
+	}
+
+	s.documents[path] = content
+}
+
+func TestLoadVerifiesLockFile(t *testing.T) {
+	server := newDocumentServer(t, map[string]string{
+		"/project.toml": "include = [\"port.toml\"]\n[service]\nname = \"tts\"\n",
+		"/port.toml":    "[service]\nport = 8080\n",
+	})
+	lockFile := filepath.Join(t.TempDir(), "project.toml.lock")
+
+	require.NoError(t, WriteLockFile(lockFile, newTestLogger(t)))
+
+	lock, readErr := readLock(lockFile)
+	require.NoError(t, readErr)
+	assert.Equal(t, server.URL+"/project.toml", lock.Source)
+	assert.Contains(t, lock.Layers, server.URL+"/port.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithLockFile(lockFile)))
+	assert.Equal(t, 8080, config.Service.Port)
+
+	server.set("/port.toml", "[service]\nport = 9090\n")
+
+	includeErr := Load(&config, newTestLogger(t), WithLockFile(lockFile))
+	require.ErrorIs(t, includeErr, ErrLockMismatch)
+	assert.ErrorContains(t, includeErr, server.URL+"/port.toml")
+
+	server.set("/project.toml", "[service]\nname = \"tts\"\n")
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithLockFile(lockFile)), ErrLockMismatch)
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithLockFile(filepath.Join(t.TempDir(), "missing.lock"))),
+		os.ErrNotExist)
+}
+
+func TestLoadRejectsOverlaysAddedOrRemovedSinceLocking(t *testing.T) {
+	server := newDocumentServer(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	lockFile := filepath.Join(t.TempDir(), "project.toml.lock")
+	opts := []Option{WithEnvironment("prod"), WithLockFile(lockFile)}
+
+	require.NoError(t, WriteLockFile(lockFile, newTestLogger(t), opts...))
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+
+	server.set("/project.prod.toml", "[service]\nport = 443\n")
+
+	addedErr := Load(&config, newTestLogger(t), opts...)
+	require.ErrorIs(t, addedErr, ErrLockMismatch)
+	assert.ErrorContains(t, addedErr, "is not in")
+
+	require.NoError(t, WriteLockFile(lockFile, newTestLogger(t), opts...))
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+	assert.Equal(t, 443, config.Service.Port)
+
+	server.set("/project.prod.toml", "")
+
+	removedErr := Load(&config, newTestLogger(t), opts...)
+	require.ErrorIs(t, removedErr, ErrLockMismatch)
+	assert.ErrorContains(t, removedErr, "no longer merged")
+}
+
+func TestVerifyLockedLayers(t *testing.T) {
+	t.Parallel()
+
+	lockFile := filepath.Join(t.TempDir(), "project.toml.lock")
+	locked := map[string]string{"a.toml": contentHash([]byte("a")), "b.toml": contentHash([]byte("b"))}
+
+	data, marshalErr := toml.Marshal(Lock{Source: "project.toml", SHA256: contentHash([]byte("base")), Layers: locked})
+	require.NoError(t, marshalErr)
+	require.NoError(t, os.WriteFile(lockFile, data, lockFilePermissions))
+
+	require.NoError(t, verifyLock(lockFile, []byte("base")))
+	require.ErrorIs(t, verifyLock(lockFile, []byte("changed")), ErrLockMismatch)
+	require.NoError(t, verifyLockedLayers(lockFile, locked))
+
+	for name, layers := range map[string]map[string]string{
+		"changed": {"a.toml": contentHash([]byte("changed")), "b.toml": locked["b.toml"]},
+		"added":   {"a.toml": locked["a.toml"], "b.toml": locked["b.toml"], "c.toml": contentHash([]byte("c"))},
+		"removed": {"a.toml": locked["a.toml"]},
+	} {
+		require.ErrorIs(t, verifyLockedLayers(lockFile, layers), ErrLockMismatch, name)
+	}
+}
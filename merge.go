@@ -0,0 +1,199 @@
+package configurator
+
+import (
+	"reflect"
+	"slices"
+)
+
+// UnsetMarker is the string value an overlay assigns to a key to remove that key, or a
+// whole table, from the base configuration: `debug = "__unset__"`.
+const UnsetMarker = "__unset__"
+
+// TableStrategy controls how a table in an overlay combines with the same table in the base.
+type TableStrategy int
+
+const (
+	// TablesDeepMerge merges tables key by key, recursively. It is the default.
+	TablesDeepMerge TableStrategy = iota
+	// TablesReplace replaces the base table with the overlay table.
+	TablesReplace
+)
+
+// ArrayStrategy controls how an array in an overlay combines with the same array in the base.
+type ArrayStrategy int
+
+const (
+	// ArraysReplace replaces the base array with the overlay array. It is the default.
+	ArraysReplace ArrayStrategy = iota
+	// ArraysAppend appends the overlay elements to the base array.
+	ArraysAppend
+	// ArraysUnion appends only the overlay elements not already present in the base array.
+	ArraysUnion
+)
+
+// NilStrategy controls what a nil value in an overlay does. TOML documents cannot contain
+// nil, but tables built in code and passed to Merge can.
+type NilStrategy int
+
+const (
+	// NilIgnore leaves the base value in place. It is the default.
+	NilIgnore NilStrategy = iota
+	// NilDelete removes the key from the result.
+	NilDelete
+	// NilOverwrite sets the key to nil in the result.
+	NilOverwrite
+)
+
+// MergeOptions selects how overlays are merged over base documents. The zero value
+// deep-merges tables, replaces arrays, and ignores nil values. Sections overrides the
+// options for the tables at the given dotted key paths and everything below them.
+type MergeOptions struct {
+	Tables   TableStrategy
+	Arrays   ArrayStrategy
+	Nil      NilStrategy
+	Sections map[string]MergeOptions
+}
+
+// WithMergeOptions sets how environment overlays, profiles, includes, and other layers
+// are merged.
+func WithMergeOptions(mergeOptions MergeOptions) Option {
+	return func(o *options) {
+		o.merge = mergeOptions
+	}
+}
+
+// Merge merges overlay over base according to mergeOptions and returns the result
+// without modifying either argument. Keys the overlay sets to UnsetMarker are removed.
+func Merge(base, overlay map[string]any, mergeOptions MergeOptions) map[string]any {
+	return mergeAt("", base, overlay, mergeOptions, mergeOptions.Sections)
+}
+
+// mergeAt merges the tables found at the dotted path prefix. sections holds the
+// top-level per-section overrides, which stay in effect while recursing.
+func mergeAt(prefix string, base, overlay map[string]any, current MergeOptions, sections map[string]MergeOptions) map[string]any {
+	merged := make(map[string]any, len(base)+len(overlay))
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, overlayValue := range overlay {
+		path := joinKey(prefix, key)
+
+		effective := current
+		if section, found := sections[path]; found {
+			effective = section
+		}
+
+		if marker, isString := overlayValue.(string); isString && marker == UnsetMarker {
+			delete(merged, key)
+
+			continue
+		}
+
+		if overlayValue == nil {
+			switch effective.Nil {
+			case NilDelete:
+				delete(merged, key)
+			case NilOverwrite:
+				merged[key] = nil
+			case NilIgnore:
+			}
+
+			continue
+		}
+
+		merged[key] = mergeValue(path, merged[key], overlayValue, effective, sections)
+	}
+
+	return merged
+}
+
+// mergeValue combines a single base value with the overlay value at path. Overlay
+// values taken over without a deep merge have their UnsetMarker keys removed, since
+// there is nothing below them to unset.
+func mergeValue(path string, baseValue, overlayValue any, effective MergeOptions, sections map[string]MergeOptions) any {
+	baseTable, baseIsTable := baseValue.(map[string]any)
+	overlayTable, overlayIsTable := overlayValue.(map[string]any)
+
+	if baseIsTable && overlayIsTable && effective.Tables == TablesDeepMerge {
+		return mergeAt(path, baseTable, overlayTable, effective, sections)
+	}
+
+	overlayValue = withoutUnset(overlayValue)
+
+	baseArray, baseIsArray := baseValue.([]any)
+	overlayArray, overlayIsArray := overlayValue.([]any)
+
+	if !baseIsArray || !overlayIsArray {
+		return overlayValue
+	}
+
+	switch effective.Arrays {
+	case ArraysAppend:
+		return slices.Concat(baseArray, overlayArray)
+	case ArraysUnion:
+		union := slices.Clone(baseArray)
+
+		for _, element := range overlayArray {
+			if !slices.ContainsFunc(union, func(existing any) bool { return reflect.DeepEqual(existing, element) }) {
+				union = append(union, element)
+			}
+		}
+
+		return union
+	case ArraysReplace:
+		return overlayValue
+	default:
+		return overlayValue
+	}
+}
+
+// withoutUnset returns a copy of value without the keys set to UnsetMarker in any table
+// within it.
+func withoutUnset(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		stripped := make(map[string]any, len(typed))
+
+		for key, nested := range typed {
+			if marker, isString := nested.(string); isString && marker == UnsetMarker {
+				continue
+			}
+
+			stripped[key] = withoutUnset(nested)
+		}
+
+		return stripped
+	case []any:
+		stripped := make([]any, len(typed))
+
+		for index, element := range typed {
+			stripped[index] = withoutUnset(element)
+		}
+
+		return stripped
+	default:
+		return value
+	}
+}
+
+// containsUnset reports whether any table within value sets a key to UnsetMarker.
+func containsUnset(value any) bool {
+	switch typed := value.(type) {
+	case map[string]any:
+		for _, nested := range typed {
+			if marker, isString := nested.(string); (isString && marker == UnsetMarker) || containsUnset(nested) {
+				return true
+			}
+		}
+	case []any:
+		for _, element := range typed {
+			if containsUnset(element) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeUnsetsKeys(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{
+		"service": map[string]any{"name": "tts", "port": int64(8080)},
+		"debug":   true,
+	}
+	overlay := map[string]any{
+		"service": map[string]any{"port": UnsetMarker},
+		"debug":   UnsetMarker,
+	}
+
+	merged := Merge(base, overlay, MergeOptions{})
+
+	assert.Equal(t, map[string]any{"service": map[string]any{"name": "tts"}}, merged)
+	assert.Equal(t, int64(8080), base["service"].(map[string]any)["port"])
+}
+
+func TestMergeStripsUnsetMarkersFromReplacedValues(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{"service": map[string]any{"name": "tts"}}
+	overlay := map[string]any{
+		"service": map[string]any{
+			"name": "stt",
+			"tls":  map[string]any{"cert": UnsetMarker, "key": "server.key"},
+			"routes": []any{
+				map[string]any{"path": "/", "auth": UnsetMarker},
+			},
+		},
+		"nats": map[string]any{"url": UnsetMarker, "subject": "jobs"},
+	}
+
+	merged := Merge(base, overlay, MergeOptions{Tables: TablesReplace})
+
+	assert.Equal(t, map[string]any{
+		"service": map[string]any{
+			"name":   "stt",
+			"tls":    map[string]any{"key": "server.key"},
+			"routes": []any{map[string]any{"path": "/"}},
+		},
+		"nats": map[string]any{"subject": "jobs"},
+	}, merged)
+	assert.False(t, containsUnset(merged))
+}
@@ -0,0 +1,96 @@
+package configurator
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// ReloadStats summarizes the reload attempts of a Watch or Store.
+type ReloadStats struct {
+	Attempts    uint64
+	Successes   uint64
+	Failures    uint64
+	LastSuccess time.Time
+	LastFailure time.Time
+	LastError   error
+}
+
+// ReloadEvent describes a single reload attempt. Changed is true when the attempt
+// published a new configuration; Err is set when it failed.
+type ReloadEvent struct {
+	Time    time.Time
+	Changed bool
+	Err     error
+	Stats   ReloadStats
+}
+
+// ReloadMetrics counts reload attempts and notifies hooks about each one. It is safe for
+// concurrent use. An attempt that finds the document unchanged counts as a success, so
+// LastSuccess tells how recently the configuration was confirmed current.
+type ReloadMetrics struct {
+	mu    sync.Mutex
+	stats ReloadStats
+	hooks []func(ReloadEvent)
+}
+
+// NewReloadMetrics returns empty ReloadMetrics to pass to WithReloadMetrics.
+func NewReloadMetrics() *ReloadMetrics {
+	return &ReloadMetrics{}
+}
+
+// WithReloadMetrics makes Watch record its reload attempts in metrics.
+func WithReloadMetrics(metrics *ReloadMetrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithReloadHook registers hook to be called after every reload attempt of a Watch or Store.
+func WithReloadHook(hook func(ReloadEvent)) Option {
+	return func(o *options) {
+		o.reloadHooks = append(o.reloadHooks, hook)
+	}
+}
+
+// OnReload registers hook to be called after every subsequent reload attempt.
+func (m *ReloadMetrics) OnReload(hook func(ReloadEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook)
+}
+
+// Stats returns a copy of the current counters.
+func (m *ReloadMetrics) Stats() ReloadStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+// record counts one reload attempt and calls the registered hooks, followed by extraHooks,
+// outside the lock.
+func (m *ReloadMetrics) record(err error, changed bool, extraHooks []func(ReloadEvent)) {
+	now := time.Now()
+
+	m.mu.Lock()
+	m.stats.Attempts++
+
+	if err != nil {
+		m.stats.Failures++
+		m.stats.LastFailure = now
+		m.stats.LastError = err
+	} else {
+		m.stats.Successes++
+		m.stats.LastSuccess = now
+	}
+
+	event := ReloadEvent{Time: now, Changed: changed, Err: err, Stats: m.stats}
+	hooks := slices.Concat(m.hooks, extraHooks)
+	m.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
@@ -0,0 +1,63 @@
+package configurator
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultChangeSubject is the NATS subject change notifications are published on when
+// WithChangeNotifier is given an empty subject.
+const DefaultChangeSubject = "book-expert.config.changed"
+
+// Publisher sends a message on a subject. *nats.Conn from github.com/nats-io/nats.go
+// satisfies it.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// ChangeNotification is the JSON payload published when the watched configuration changes.
+type ChangeNotification struct {
+	Source      string    `json:"source"`
+	Hash        string    `json:"hash"`
+	LoadedAt    time.Time `json:"loaded_at"`
+	ChangedKeys []string  `json:"changed_keys,omitempty"`
+}
+
+// WithChangeNotifier makes Watch publish a ChangeNotification on subject through
+// publisher whenever the configuration changes, so downstream services can re-fetch
+// immediately instead of polling.
+func WithChangeNotifier(publisher Publisher, subject string) Option {
+	return func(o *options) {
+		if subject == "" {
+			subject = DefaultChangeSubject
+		}
+
+		o.publisher = publisher
+		o.changeSubject = subject
+	}
+}
+
+// notifyChange publishes a notification for update when a publisher is configured.
+// Failures are logged rather than returned so they never block a reload.
+func (w *watcher[T]) notifyChange(update Update[T]) {
+	if w.settings.publisher == nil {
+		return
+	}
+
+	payload, marshalErr := json.Marshal(ChangeNotification{
+		Source:      update.Source,
+		Hash:        update.Hash,
+		LoadedAt:    update.LoadedAt,
+		ChangedKeys: update.ChangedKeys,
+	})
+	if marshalErr != nil {
+		w.logger.Error("failed to encode change notification: %v", marshalErr)
+
+		return
+	}
+
+	publishErr := w.settings.publisher.Publish(w.settings.changeSubject, payload)
+	if publishErr != nil {
+		w.logger.Error("failed to publish change notification on %s: %v", w.settings.changeSubject, publishErr)
+	}
+}
@@ -0,0 +1,259 @@
+package configurator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenExpiryMargin is how long before its expiry a cached token is replaced.
+	tokenExpiryMargin = 30 * time.Second
+	// tokenMarginDivisor bounds the margin of short-lived tokens to this fraction of their
+	// lifetime, so they are still used for most of it.
+	tokenMarginDivisor = 4
+	// defaultTokenLifetime is assumed for tokens whose response omits expires_in.
+	defaultTokenLifetime = 5 * time.Minute
+	// oidcDiscoveryPath is appended to an issuer to find its OpenID configuration.
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// ErrOAuth2Token is returned when an OAuth2 access token cannot be obtained.
+var ErrOAuth2Token = errors.New("failed to obtain OAuth2 token")
+
+// OAuth2Config describes an OAuth2 client-credentials grant. TokenURL may be left empty
+// when Issuer names an OpenID Connect provider, whose token endpoint is then discovered.
+// Audience is sent for providers that require it.
+type OAuth2Config struct {
+	TokenURL     string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Audience     string
+}
+
+// WithOAuth2 authenticates configuration requests with an access token obtained through
+// the client-credentials grant. Tokens are cached per client and scope set, renewed
+// shortly before they expire, and dropped when the server answers 401 Unauthorized, in
+// which case the fetch is retried with a new token under the retry policy.
+func WithOAuth2(config OAuth2Config) Option {
+	return func(o *options) {
+		o.oauth2 = &config
+	}
+}
+
+// cachedToken is an access token with the time it stops being usable.
+type cachedToken struct {
+	value  string
+	expiry time.Time
+}
+
+// tokenSlot holds the token of one OAuth2 client. Its mutex is held while the token is
+// renewed, so concurrent loads for that client share one request without holding up
+// loads for other clients.
+type tokenSlot struct {
+	mu    sync.Mutex
+	token cachedToken
+}
+
+// oauth2Tokens holds the tokens of every OAuth2 client used in the process, so Watch
+// and repeated loads reuse them until they expire.
+var oauth2Tokens = struct {
+	mu    sync.Mutex
+	slots map[string]*tokenSlot
+}{slots: make(map[string]*tokenSlot)}
+
+// tokenSlot returns the slot of the OAuth2 client of o, creating it when needed. The
+// key covers a hash of the client secret, so a rotated secret gets a new token.
+func (o *options) tokenSlot() *tokenSlot {
+	config := o.oauth2
+	secret := sha256.Sum256([]byte(config.ClientSecret))
+	key := strings.Join([]string{
+		config.TokenURL, config.Issuer, config.ClientID, hex.EncodeToString(secret[:]),
+		strings.Join(config.Scopes, " "), config.Audience,
+	}, "\n")
+
+	oauth2Tokens.mu.Lock()
+	defer oauth2Tokens.mu.Unlock()
+
+	slot, found := oauth2Tokens.slots[key]
+	if !found {
+		slot = &tokenSlot{}
+		oauth2Tokens.slots[key] = slot
+	}
+
+	return slot
+}
+
+// applyOAuth2 sets an Authorization header with the OAuth2 access token, requesting a
+// new token with client when there is no usable cached one.
+func (o *options) applyOAuth2(ctx context.Context, req *http.Request, client *http.Client) error {
+	if o.oauth2 == nil {
+		return nil
+	}
+
+	slot := o.tokenSlot()
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.token.value == "" || time.Now().After(slot.token.expiry) {
+		requested, tokenErr := requestToken(ctx, client, o.oauth2)
+		if tokenErr != nil {
+			return tokenErr
+		}
+
+		slot.token = requested
+	}
+
+	req.Header.Set("Authorization", "Bearer "+slot.token.value)
+
+	return nil
+}
+
+// evictOAuth2Token drops the cached token that req was sent with after the server
+// rejected it, so the next request obtains a new one. It reports whether a token was
+// evicted.
+func (o *options) evictOAuth2Token(req *http.Request) bool {
+	if o.oauth2 == nil {
+		return false
+	}
+
+	sent, isBearer := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !isBearer {
+		return false
+	}
+
+	slot := o.tokenSlot()
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.token.value != sent {
+		return false
+	}
+
+	slot.token = cachedToken{}
+
+	return true
+}
+
+// requestToken performs the client-credentials grant.
+func requestToken(ctx context.Context, client *http.Client, config *OAuth2Config) (cachedToken, error) {
+	tokenURL := config.TokenURL
+	if tokenURL == "" {
+		discovered, discoverErr := discoverTokenEndpoint(ctx, client, config.Issuer)
+		if discoverErr != nil {
+			return cachedToken{}, discoverErr
+		}
+
+		tokenURL = discovered
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+
+	if config.Audience != "" {
+		form.Set("audience", config.Audience)
+	}
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if newRequestErr != nil {
+		return cachedToken{}, fmt.Errorf("%w: %w", ErrOAuth2Token, newRequestErr)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	exchangeErr := exchangeJSON(client, req, &response)
+	if exchangeErr != nil {
+		return cachedToken{}, fmt.Errorf("%w: %w", ErrOAuth2Token, exchangeErr)
+	}
+
+	if response.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("%w: response has no access_token", ErrOAuth2Token)
+	}
+
+	return cachedToken{value: response.AccessToken, expiry: tokenExpiry(response.ExpiresIn, time.Now())}, nil
+}
+
+// tokenExpiry returns when a token issued at now for expiresIn seconds is replaced:
+// tokenExpiryMargin before it expires, or after three quarters of its lifetime when that
+// is sooner. Tokens without expires_in are assumed to live for defaultTokenLifetime.
+func tokenExpiry(expiresIn int64, now time.Time) time.Time {
+	lifetime := time.Duration(expiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+
+	return now.Add(lifetime - min(tokenExpiryMargin, lifetime/tokenMarginDivisor))
+}
+
+// discoverTokenEndpoint reads the token endpoint from the issuer's OpenID configuration.
+func discoverTokenEndpoint(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	if issuer == "" {
+		return "", fmt.Errorf("%w: neither TokenURL nor Issuer is set", ErrOAuth2Token)
+	}
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+oidcDiscoveryPath, nil)
+	if newRequestErr != nil {
+		return "", fmt.Errorf("%w: %w", ErrOAuth2Token, newRequestErr)
+	}
+
+	var discovery struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+
+	exchangeErr := exchangeJSON(client, req, &discovery)
+	if exchangeErr != nil {
+		return "", fmt.Errorf("%w: OpenID discovery: %w", ErrOAuth2Token, exchangeErr)
+	}
+
+	if discovery.TokenEndpoint == "" {
+		return "", fmt.Errorf("%w: issuer %s has no token_endpoint", ErrOAuth2Token, issuer)
+	}
+
+	return discovery.TokenEndpoint, nil
+}
+
+// exchangeJSON sends req and decodes its successful JSON response into target.
+func exchangeJSON(client *http.Client, req *http.Request, target any) error {
+	resp, doRequestErr := client.Do(req)
+	if doRequestErr != nil {
+		return fmt.Errorf("failed to execute HTTP request: %w", doRequestErr)
+	}
+
+	body, processResponseErr := processResponse(resp, DefaultMaxBodySize)
+
+	closeErr := resp.Body.Close()
+	if processResponseErr != nil {
+		return processResponseErr
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("failed to close response body: %w", closeErr)
+	}
+
+	unmarshalErr := json.Unmarshal(body, target)
+	if unmarshalErr != nil {
+		return fmt.Errorf("failed to decode response: %w", unmarshalErr)
+	}
+
+	return nil
+}
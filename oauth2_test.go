@@ -0,0 +1,252 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer grants client-credentials tokens numbered from 1 to the client secret it
+// was given, valid for expiresIn seconds. It also serves an OpenID configuration
+// pointing at its token endpoint.
+type tokenServer struct {
+	*httptest.Server
+
+	grants    atomic.Int64
+	expiresIn int64
+
+	mu   sync.Mutex
+	form map[string][]string
+}
+
+func newTokenServer(t *testing.T, secret string, expiresIn int64) *tokenServer {
+	t.Helper()
+
+	tokens := &tokenServer{expiresIn: expiresIn}
+	tokens.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == oidcDiscoveryPath {
+			_ = json.NewEncoder(w).Encode(map[string]string{"token_endpoint": tokens.URL + "/token"})
+
+			return
+		}
+
+		_, password, hasAuth := r.BasicAuth()
+		if r.URL.Path != "/token" || !hasAuth || password != secret || r.ParseForm() != nil {
+			http.Error(w, "invalid_client", http.StatusUnauthorized)
+
+			return
+		}
+
+		tokens.mu.Lock()
+		tokens.form = r.PostForm
+		tokens.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", tokens.grants.Add(1)),
+			"expires_in":   tokens.expiresIn,
+		})
+	}))
+	t.Cleanup(tokens.Close)
+
+	return tokens
+}
+
+// lastForm returns the form of the last token request.
+func (s *tokenServer) lastForm() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.form
+}
+
+// bearerHandler serves a document to requests whose bearer token is not in rejected,
+// recording the tokens it sees.
+func bearerHandler(rejected string, seen *[]string, mu *sync.Mutex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+
+		mu.Lock()
+		*seen = append(*seen, token)
+		mu.Unlock()
+
+		if token == "" || token == "Bearer "+rejected {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("[service]\nname = \"tts\"\n"))
+	})
+}
+
+// protectedServer serves a document to requests whose bearer token is not rejected.
+func protectedServer(t *testing.T, rejected string) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+
+	server := httptest.NewServer(bearerHandler(rejected, &seen, &mu))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestOAuth2TokenGrant(t *testing.T) {
+	tokens := newTokenServer(t, "secret", 3600)
+	server, seen := protectedServer(t, "")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	oauth2 := WithOAuth2(OAuth2Config{
+		TokenURL:     tokens.URL + "/token",
+		ClientID:     t.Name(),
+		ClientSecret: "secret",
+		Scopes:       []string{"config.read", "config.list"},
+		Audience:     "config-service",
+	})
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, []string{"Bearer token-1"}, seen())
+
+	form := tokens.lastForm()
+	assert.Equal(t, []string{"client_credentials"}, form["grant_type"])
+	assert.Equal(t, []string{"config.read config.list"}, form["scope"])
+	assert.Equal(t, []string{"config-service"}, form["audience"])
+
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2))
+	assert.Equal(t, int64(1), tokens.grants.Load(), "the cached token is reused")
+}
+
+func TestOAuth2DiscoversTokenEndpoint(t *testing.T) {
+	tokens := newTokenServer(t, "secret", 3600)
+	server, seen := protectedServer(t, "")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithOAuth2(OAuth2Config{Issuer: tokens.URL + "/", ClientID: t.Name(), ClientSecret: "secret"})))
+	assert.Equal(t, []string{"Bearer token-1"}, seen())
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithOAuth2(OAuth2Config{ClientID: t.Name()})), ErrOAuth2Token)
+}
+
+func TestOAuth2RenewsBeforeExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	assert.Equal(t, now.Add(time.Hour-tokenExpiryMargin), tokenExpiry(3600, now))
+	assert.Equal(t, now.Add(15*time.Second), tokenExpiry(20, now), "short-lived tokens are not expired on arrival")
+	assert.Equal(t, now.Add(defaultTokenLifetime-tokenExpiryMargin), tokenExpiry(0, now))
+}
+
+func TestOAuth2RenewsExpiredToken(t *testing.T) {
+	tokens := newTokenServer(t, "secret", 1)
+	server, seen := protectedServer(t, "")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	oauth2 := WithOAuth2(OAuth2Config{TokenURL: tokens.URL + "/token", ClientID: t.Name(), ClientSecret: "secret"})
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2))
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2))
+	time.Sleep(time.Second)
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2))
+
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}, seen())
+}
+
+func TestOAuth2EvictsRejectedToken(t *testing.T) {
+	tokens := newTokenServer(t, "secret", 3600)
+	server, seen := protectedServer(t, "token-1")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	oauth2 := WithOAuth2(OAuth2Config{TokenURL: tokens.URL + "/token", ClientID: t.Name(), ClientSecret: "secret"})
+
+	var config testConfig
+
+	require.Error(t, Load(&config, newTestLogger(t), oauth2, WithRetry(RetryPolicy{Attempts: 1})))
+	require.NoError(t, Load(&config, newTestLogger(t), oauth2, WithRetry(RetryPolicy{Attempts: 1})))
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, seen())
+
+	revoked := newTokenServer(t, "secret", 3600)
+	retried, retriedSeen := protectedServer(t, "token-1")
+	t.Setenv("PROJECT_TOML", retried.URL+"/project.toml")
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithOAuth2(OAuth2Config{TokenURL: revoked.URL + "/token", ClientID: t.Name(), ClientSecret: "secret"}),
+		WithRetry(RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond})))
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, retriedSeen(), "a retry fetches with a new token")
+}
+
+func TestOAuth2RotatedSecretGetsNewToken(t *testing.T) {
+	rotated := newTokenServer(t, "rotated", 3600)
+	server, seen := protectedServer(t, "")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	tokenURL := rotated.URL + "/token"
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t),
+		WithOAuth2(OAuth2Config{TokenURL: tokenURL, ClientID: t.Name(), ClientSecret: "old"})), ErrOAuth2Token)
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithOAuth2(OAuth2Config{TokenURL: tokenURL, ClientID: t.Name(), ClientSecret: "rotated"})))
+	assert.Equal(t, []string{"Bearer token-1"}, seen())
+}
+
+func TestOAuth2TokenIsNotRequestedOverSocket(t *testing.T) {
+	socket := filepath.Join(os.TempDir(), fmt.Sprintf("configurator-%d.sock", os.Getpid()))
+	t.Cleanup(func() { _ = os.Remove(socket) })
+
+	listener, listenErr := net.Listen(unixScheme, socket)
+	require.NoError(t, listenErr)
+
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+
+	server := httptest.NewUnstartedServer(bearerHandler("", &seen, &mu))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	tokens := newTokenServer(t, "secret", 3600)
+	t.Setenv("PROJECT_TOML", unixScheme+"://"+socket+":/project.toml")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithOAuth2(OAuth2Config{TokenURL: tokens.URL + "/token", ClientID: t.Name(), ClientSecret: "secret"})))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, int64(1), tokens.grants.Load())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"Bearer token-1"}, seen)
+}
@@ -0,0 +1,58 @@
+package configurator
+
+import "time"
+
+// OnChange consumes updates from Watch and calls callback with the previous and the new
+// configuration. The first configuration received is the baseline and does not trigger
+// the callback. Changes arriving within debounce of each other are coalesced so a burst
+// of edits results in a single call. Error updates are skipped. OnChange returns
+// immediately and stops once updates is closed.
+func OnChange[T any](updates <-chan Update[T], debounce time.Duration, callback func(previous, current *T)) {
+	go dispatchChanges(updates, debounce, callback)
+}
+
+// dispatchChanges implements the debounce loop behind OnChange.
+func dispatchChanges[T any](updates <-chan Update[T], debounce time.Duration, callback func(previous, current *T)) {
+	var (
+		current *T
+		pending *T
+		timer   *time.Timer
+		fire    <-chan time.Time
+	)
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				if pending != nil {
+					callback(current, pending)
+				}
+
+				return
+			}
+
+			if update.Err != nil {
+				continue
+			}
+
+			if current == nil && pending == nil {
+				current = update.Config
+
+				continue
+			}
+
+			pending = update.Config
+
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+
+			fire = timer.C
+		case <-fire:
+			callback(current, pending)
+			current, pending, fire = pending, nil, nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package configurator
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeLog records the calls OnChange made.
+type changeLog struct {
+	mu    sync.Mutex
+	calls [][2]string
+}
+
+// record is an OnChange callback logging the names of previous and current.
+func (l *changeLog) record(previous, current *testConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls = append(l.calls, [2]string{previous.Service.Name, current.Service.Name})
+}
+
+// snapshot returns the calls made so far.
+func (l *changeLog) snapshot() [][2]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([][2]string(nil), l.calls...)
+}
+
+// named returns an update carrying a configuration called name.
+func named(name string) Update[testConfig] {
+	config := &testConfig{}
+	config.Service.Name = name
+
+	return Update[testConfig]{Config: config}
+}
+
+func TestOnChangeCoalescesBursts(t *testing.T) {
+	t.Parallel()
+
+	updates := make(chan Update[testConfig])
+
+	var log changeLog
+
+	OnChange(updates, 50*time.Millisecond, log.record)
+
+	updates <- named("v1")
+	updates <- named("v2")
+	updates <- Update[testConfig]{Err: errors.New("fetch failed")}
+	updates <- named("v3")
+	updates <- named("v4")
+
+	require.Eventually(t, func() bool {
+		return len(log.snapshot()) == 1
+	}, eventually, tick)
+	assert.Equal(t, [][2]string{{"v1", "v4"}}, log.snapshot())
+
+	updates <- named("v5")
+
+	require.Eventually(t, func() bool {
+		return len(log.snapshot()) == 2
+	}, eventually, tick)
+	assert.Equal(t, [2]string{"v4", "v5"}, log.snapshot()[1])
+}
+
+func TestOnChangeSkipsTheBaseline(t *testing.T) {
+	t.Parallel()
+
+	updates := make(chan Update[testConfig])
+
+	var log changeLog
+
+	OnChange(updates, time.Millisecond, log.record)
+
+	updates <- Update[testConfig]{Err: errors.New("fetch failed")}
+	updates <- named("v1")
+
+	time.Sleep(quiet)
+	assert.Empty(t, log.snapshot())
+}
+
+func TestOnChangeDeliversPendingChangeWhenClosed(t *testing.T) {
+	t.Parallel()
+
+	updates := make(chan Update[testConfig])
+
+	var log changeLog
+
+	OnChange(updates, time.Hour, log.record)
+
+	updates <- named("v1")
+	updates <- named("v2")
+	close(updates)
+
+	require.Eventually(t, func() bool {
+		return len(log.snapshot()) == 1
+	}, eventually, tick)
+	assert.Equal(t, [][2]string{{"v1", "v2"}}, log.snapshot())
+}
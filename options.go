@@ -0,0 +1,125 @@
+package configurator
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Option customizes how Load and Watch fetch and verify configuration.
+type Option func(*options)
+
+// options holds the settings assembled from the Option values passed to Load.
+type options struct {
+	lockFile           string
+	vendoredCopy       string
+	pollInterval       time.Duration
+	reloadSignals      []os.Signal
+	historySize        int
+	awaitApply         bool
+	publisher          Publisher
+	changeSubject      string
+	reloadTrigger      *ReloadTrigger
+	validations        []func(config any) error
+	metrics            *ReloadMetrics
+	reloadHooks        []func(ReloadEvent)
+	environment        string
+	profile            string
+	localOverride      bool
+	maxIncludeDepth    int
+	merge              MergeOptions
+	precedence         []Layer
+	systemConfigFile   string
+	userConfigFile     string
+	defaultsFS         fs.FS
+	defaultsPath       string
+	secretKey          []byte
+	kms                KMS
+	kmsKeyID           string
+	secretResolvers    map[string]SecretResolver
+	signatureKeyring   string
+	cosignPublicKey    string
+	allowedHosts       []string
+	requireHTTPS       bool
+	blockInternal      bool
+	maxBodySize        int64
+	clientCert         string
+	clientKey          string
+	caBundle           string
+	bearerToken        string
+	username           string
+	password           string
+	headers            http.Header
+	credentialHosts    []string
+	oauth2             *OAuth2Config
+	sigV4              bool
+	sigV4Region        string
+	strictPermissions  bool
+	urlTimeout         time.Duration
+	loadContext        context.Context
+	retry              RetryPolicy
+	breakerThreshold   int
+	breakerCooldown    time.Duration
+	httpClient         *http.Client
+	redirects          RedirectPolicy
+	maxParallelFetches int
+	userAgent          string
+	progress           func(Progress) error
+	cacheDir           string
+	cacheKey           []byte
+	plaintextCache     bool
+	offline            bool
+	staleFallback      bool
+	maxStale           time.Duration
+}
+
+// newOptions applies the given Option values over the defaults.
+func newOptions(opts []Option) *options {
+	settings := &options{
+		pollInterval:       DefaultPollInterval,
+		historySize:        DefaultHistorySize,
+		maxIncludeDepth:    DefaultMaxIncludeDepth,
+		precedence:         lowestFirst(DefaultPrecedence()),
+		allowedHosts:       defaultAllowedHosts(),
+		requireHTTPS:       defaultRequireHTTPS(),
+		blockInternal:      defaultBlockInternal(),
+		maxBodySize:        DefaultMaxBodySize,
+		urlTimeout:         defaultURLTimeout(),
+		loadContext:        context.Background(),
+		retry:              defaultRetryPolicy(),
+		breakerThreshold:   DefaultBreakerThreshold,
+		breakerCooldown:    DefaultBreakerCooldown,
+		maxParallelFetches: DefaultMaxParallelFetches,
+		userAgent:          defaultUserAgent(),
+		cacheDir:           os.Getenv(CacheDirVariable),
+		plaintextCache:     defaultPlaintextCache(),
+		offline:            defaultOffline(),
+		clientCert:         os.Getenv(ClientCertVariable),
+		clientKey:          os.Getenv(ClientKeyVariable),
+		caBundle:           os.Getenv(CABundleVariable),
+		bearerToken:        os.Getenv(TokenVariable),
+		username:           os.Getenv(UsernameVariable),
+		password:           os.Getenv(PasswordVariable),
+		credentialHosts:    hostList(os.Getenv(CredentialHostsVariable)),
+		sigV4:              defaultSigV4(),
+		strictPermissions:  defaultStrictPermissions(),
+	}
+
+	settings.staleFallback, settings.maxStale = defaultStaleFallback()
+
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	if settings.metrics == nil {
+		settings.metrics = NewReloadMetrics()
+	}
+
+	if settings.cacheDir == "" && (settings.offline || settings.staleFallback) {
+		settings.cacheDir = defaultCacheDir()
+	}
+
+	return settings
+}
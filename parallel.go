@@ -0,0 +1,74 @@
+package configurator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxParallelFetches limits how many sources a load reads at the same time.
+const DefaultMaxParallelFetches = 8
+
+// SourceError reports the failure to read or resolve one source of the configuration.
+// When several sources fail in the same load, their errors are joined, so each can be
+// inspected separately.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+// Error returns the source followed by the error it failed with.
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// WithMaxParallelFetches sets how many includes and secret references a load reads
+// concurrently, DefaultMaxParallelFetches by default.
+func WithMaxParallelFetches(limit int) Option {
+	return func(o *options) {
+		o.maxParallelFetches = max(limit, 1)
+	}
+}
+
+// fetchLimiter bounds the number of concurrent reads of one load.
+type fetchLimiter chan struct{}
+
+// newFetchLimiter returns a limiter admitting limit concurrent reads.
+func newFetchLimiter(limit int) fetchLimiter {
+	return make(fetchLimiter, max(limit, 1))
+}
+
+// do runs read once a slot is free.
+func (l fetchLimiter) do(read func()) {
+	l <- struct{}{}
+	defer func() { <-l }()
+
+	read()
+}
+
+// runParallel calls work for every index below count on at most limit goroutines and
+// waits for all of them.
+func runParallel(count, limit int, work func(int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(count, max(limit, 1)) {
+		wg.Go(func() {
+			for index := range indexes {
+				work(index)
+			}
+		})
+	}
+
+	for index := range count {
+		indexes <- index
+	}
+
+	close(indexes)
+	wg.Wait()
+}
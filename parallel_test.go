@@ -0,0 +1,131 @@
+package configurator
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceErrors returns the sources of every SourceError in err's tree, outermost first.
+func sourceErrors(err error) []string {
+	switch wrapped := err.(type) {
+	case *SourceError:
+		return []string{wrapped.Source}
+	case interface{ Unwrap() []error }:
+		var sources []string
+		for _, inner := range wrapped.Unwrap() {
+			sources = append(sources, sourceErrors(inner)...)
+		}
+
+		return sources
+	case interface{ Unwrap() error }:
+		return sourceErrors(wrapped.Unwrap())
+	default:
+		return nil
+	}
+}
+
+// peakCounter records the highest number of callers between enter and the function it
+// returns.
+type peakCounter struct {
+	inFlight, peak atomic.Int32
+}
+
+// enter counts a caller in and returns the function counting it out.
+func (c *peakCounter) enter() func() {
+	current := c.inFlight.Add(1)
+
+	for {
+		highest := c.peak.Load()
+		if current <= highest || c.peak.CompareAndSwap(highest, current) {
+			return func() { c.inFlight.Add(-1) }
+		}
+	}
+}
+
+func TestLoadReportsEveryFailingInclude(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "include = [\"missing.toml\", \"broken.toml\", \"nats.toml\"]\n",
+		"/broken.toml":  "[service\n",
+		"/nats.toml":    "[nats]\nurl = \"nats://localhost\"\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	loadErr := Load(&config, newTestLogger(t))
+	require.ErrorIs(t, loadErr, ErrSourceNotFound)
+
+	var sourceErr *SourceError
+	require.ErrorAs(t, loadErr, &sourceErr)
+	assert.Equal(t, []string{server.URL + "/missing.toml", server.URL + "/broken.toml"}, sourceErrors(loadErr))
+	assert.ErrorContains(t, loadErr, "failed to parse include")
+}
+
+func TestLoadLimitsParallelFetches(t *testing.T) {
+	var concurrency peakCounter
+
+	includes := make([]string, 6)
+	for index := range includes {
+		includes[index] = fmt.Sprintf("%q", fmt.Sprintf("part%d.toml", index))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/project.toml" {
+			_, _ = fmt.Fprintf(w, "include = [%s]\n", strings.Join(includes, ", "))
+
+			return
+		}
+
+		defer concurrency.enter()()
+
+		time.Sleep(20 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, "[parts]\n%s = true\n", strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".toml"))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithMaxParallelFetches(2)))
+	assert.Len(t, config["parts"], len(includes))
+	assert.LessOrEqual(t, concurrency.peak.Load(), int32(2))
+}
+
+func TestRunParallelVisitsEveryIndexOnce(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu          sync.Mutex
+		visits      = make(map[int]int)
+		concurrency peakCounter
+	)
+
+	runParallel(20, 3, func(index int) {
+		defer concurrency.enter()()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		visits[index]++
+		mu.Unlock()
+	})
+
+	assert.Len(t, visits, 20)
+
+	for index, count := range visits {
+		assert.Equal(t, 1, count, index)
+	}
+
+	assert.LessOrEqual(t, concurrency.peak.Load(), int32(3))
+
+	runParallel(0, 0, func(int) { t.Error("no work expected") })
+}
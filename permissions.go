@@ -0,0 +1,174 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/book-expert/logger"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// StrictPermissionsVariable names the environment variable that, when true, fails loads
+// whose secret-holding files are readable by others, used when WithStrictPermissions is
+// not given.
+const StrictPermissionsVariable = "PROJECT_TOML_STRICT_PERMISSIONS"
+
+// ErrInsecurePermissions is returned in strict mode when a local file holding secret keys
+// is readable by its group or by others.
+var ErrInsecurePermissions = errors.New("configuration file holding secrets is readable by others")
+
+// WithStrictPermissions fails the load instead of logging a warning when a local file that
+// sets a field tagged secret:"true" is group- or world-readable.
+func WithStrictPermissions(strict bool) Option {
+	return func(o *options) {
+		o.strictPermissions = strict
+	}
+}
+
+// defaultStrictPermissions reports whether StrictPermissionsVariable is set to true.
+func defaultStrictPermissions() bool {
+	strict, _ := strconv.ParseBool(os.Getenv(StrictPermissionsVariable))
+
+	return strict
+}
+
+// auditPermissions checks, like ssh does for private keys, that the local files which set
+// secret-tagged fields of target are not readable by group or others. The fields are
+// looked up below each of the given tables, "" being the document root. Offending files
+// are logged, or fail the load in strict mode.
+func auditPermissions(files []string, target any, tables []string, settings *options, logger *logger.Logger) error {
+	keys := secretKeys(reflect.TypeOf(target), "", make(map[reflect.Type]bool))
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for _, path := range files {
+		info, statErr := os.Stat(path)
+		if statErr != nil || !exposedToOthers(info) {
+			continue
+		}
+
+		if !holdsAnyKey(path, tables, keys) {
+			continue
+		}
+
+		if settings.strictPermissions {
+			return fmt.Errorf("%w: %s has mode %04o", ErrInsecurePermissions, path, info.Mode().Perm())
+		}
+
+		logger.Warn("%s holds secrets but has mode %04o; restrict it with chmod 600", path, info.Mode().Perm())
+	}
+
+	return nil
+}
+
+// holdsAnyKey reports whether the TOML file at path sets any of keys below one of tables.
+// Files that cannot be parsed as plain TOML, such as encrypted ones, hold no readable
+// secrets.
+func holdsAnyKey(path string, tables, keys []string) bool {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return false
+	}
+
+	table := make(map[string]any)
+
+	unmarshalErr := toml.Unmarshal(content, &table)
+	if unmarshalErr != nil {
+		return false
+	}
+
+	for _, root := range tables {
+		for _, key := range keys {
+			if hasKey(table, strings.Split(joinKey(root, key), ".")) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasKey reports whether value holds the dotted key split into segments, matching names
+// case-insensitively as unmarshalling does and searching every element of arrays.
+func hasKey(value any, segments []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		for name, child := range typed {
+			if strings.EqualFold(name, segments[0]) && hasKey(child, segments[1:]) {
+				return true
+			}
+		}
+	case []any:
+		for _, element := range typed {
+			if hasKey(element, segments) {
+				return true
+			}
+		}
+	case []map[string]any:
+		for _, element := range typed {
+			if hasKey(element, segments) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// secretKeys returns the dotted TOML keys of the fields tagged secret:"true" within
+// the type t, at any depth. seen guards against recursive types.
+func secretKeys(t reflect.Type, prefix string, seen map[reflect.Type]bool) []string {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	var keys []string
+
+	for index := range t.NumField() {
+		field := t.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" && field.Anonymous {
+			keys = append(keys, secretKeys(field.Type, prefix, seen)...)
+
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if field.Tag.Get(secretTag) == "true" {
+			keys = append(keys, joinKey(prefix, name))
+
+			continue
+		}
+
+		keys = append(keys, secretKeys(field.Type, joinKey(prefix, name), seen)...)
+	}
+
+	return keys
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package configurator
+
+import "io/fs"
+
+// exposedToOthers reports false, since the permission bits do not describe who may read
+// a file on this platform.
+func exposedToOthers(fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,105 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// credentialsConfig has secret fields at the root, in a nested table, and in a table array.
+type credentialsConfig struct {
+	Token string `secret:"true" toml:"token"`
+	NATS  struct {
+		URL      string `toml:"url"`
+		Password string `secret:"true" toml:"password"`
+	} `toml:"nats"`
+	Upstreams []struct {
+		Key string `secret:"true" toml:"key"`
+	} `toml:"upstreams"`
+}
+
+// credentialsFile writes content with mode to a new PROJECT_TOML file.
+func credentialsFile(t *testing.T, content string, mode os.FileMode) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+	require.NoError(t, os.Chmod(path, mode))
+	t.Setenv("PROJECT_TOML", path)
+}
+
+func TestLoadAuditsFilesHoldingSecrets(t *testing.T) {
+	t.Setenv(StrictPermissionsVariable, "")
+
+	for _, content := range []string{
+		"token = \"t\"\n",
+		"[NATS]\nPassword = \"p\"\n",
+		"[[upstreams]]\nkey = \"k\"\n",
+	} {
+		credentialsFile(t, content, 0o644)
+
+		var config credentialsConfig
+
+		loadErr := Load(&config, newTestLogger(t), WithStrictPermissions(true))
+		require.ErrorIs(t, loadErr, ErrInsecurePermissions, content)
+		assert.ErrorContains(t, loadErr, "0644")
+
+		require.NoError(t, Load(&config, newTestLogger(t)), "only warns unless strict")
+	}
+}
+
+func TestLoadAcceptsPrivateOrSecretFreeFiles(t *testing.T) {
+	t.Setenv(StrictPermissionsVariable, "true")
+
+	var config credentialsConfig
+
+	credentialsFile(t, "token = \"t\"\n", 0o600)
+	require.NoError(t, Load(&config, newTestLogger(t)))
+
+	credentialsFile(t, "[nats]\nurl = \"nats://localhost\"\n", 0o644)
+	require.NoError(t, Load(&config, newTestLogger(t)))
+
+	credentialsFile(t, "token = \"t\"\n", 0o644)
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrInsecurePermissions)
+	require.NoError(t, Load(&config, newTestLogger(t), WithStrictPermissions(false)))
+}
+
+func TestLoadServiceAuditsServiceTables(t *testing.T) {
+	type serviceCredentials struct {
+		Password string `secret:"true" toml:"password"`
+	}
+
+	t.Setenv(StrictPermissionsVariable, "")
+
+	var config serviceCredentials
+
+	credentialsFile(t, "[common]\npassword = \"p\"\n[tts]\nport = 1\n", 0o640)
+	require.ErrorIs(t, LoadService("tts", &config, newTestLogger(t), WithStrictPermissions(true)), ErrInsecurePermissions)
+
+	credentialsFile(t, "[ocr]\npassword = \"p\"\n[tts]\nport = 1\n", 0o640)
+	require.NoError(t, LoadService("tts", &config, newTestLogger(t), WithStrictPermissions(true)))
+}
+
+func TestSecretKeys(t *testing.T) {
+	t.Parallel()
+
+	type Embedded struct {
+		APIKey string `secret:"true"`
+	}
+
+	type node struct {
+		Embedded
+
+		Secret   string `secret:"true" toml:"secret,omitempty"`
+		Ignored  string `secret:"true" toml:"-"`
+		Children []*node
+		hidden   string `secret:"true"`
+	}
+
+	assert.Equal(t, []string{"APIKey", "secret"}, secretKeys(reflect.TypeFor[*node](), "", make(map[reflect.Type]bool)))
+	assert.Empty(t, secretKeys(reflect.TypeFor[string](), "", make(map[reflect.Type]bool)))
+}
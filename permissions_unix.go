@@ -0,0 +1,13 @@
+//go:build unix
+
+package configurator
+
+import "io/fs"
+
+// groupOrOtherReadBits are the permission bits that let others than the owner read a file.
+const groupOrOtherReadBits = 0o044
+
+// exposedToOthers reports whether the file is readable by its group or by others.
+func exposedToOthers(info fs.FileInfo) bool {
+	return info.Mode().Perm()&groupOrOtherReadBits != 0
+}
@@ -0,0 +1,45 @@
+package configurator
+
+import "slices"
+
+// Layer identifies a source of configuration values that Load merges.
+type Layer string
+
+const (
+	// LayerBase is the document named by PROJECT_TOML (or its vendored copy) with its includes.
+	LayerBase Layer = "base"
+	// LayerEnvironment is the overlay selected by APP_ENV, e.g. project.prod.toml.
+	LayerEnvironment Layer = "environment"
+	// LayerProfile is the [profile.<name>] table selected by PROJECT_TOML_PROFILE.
+	LayerProfile Layer = "profile"
+	// LayerLocal is the untracked project.local.toml override.
+	LayerLocal Layer = "local"
+	// LayerUser is the per-user file, ~/.config/book-expert/project.toml when enabled.
+	LayerUser Layer = "user"
+	// LayerSystem is the machine-wide file, /etc/book-expert/project.toml when enabled.
+	LayerSystem Layer = "system"
+	// LayerDefaults is the built-in defaults document set with WithDefaults.
+	LayerDefaults Layer = "defaults"
+)
+
+// DefaultPrecedence returns the layers Load merges, highest precedence first.
+func DefaultPrecedence() []Layer {
+	return []Layer{LayerLocal, LayerProfile, LayerEnvironment, LayerBase, LayerUser, LayerSystem, LayerDefaults}
+}
+
+// WithPrecedence declares which layers Load merges and in what order, highest precedence
+// first, e.g. WithPrecedence(LayerLocal, LayerEnvironment, LayerBase). Layers that are
+// not listed are ignored.
+func WithPrecedence(layers ...Layer) Option {
+	return func(o *options) {
+		o.precedence = lowestFirst(layers)
+	}
+}
+
+// lowestFirst returns layers, given highest precedence first, in merge order.
+func lowestFirst(layers []Layer) []Layer {
+	ordered := slices.Clone(layers)
+	slices.Reverse(ordered)
+
+	return ordered
+}
@@ -0,0 +1,103 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layeredProject writes a document for every layer into a temporary directory, each
+// setting winner to its own layer name and a key that only that layer sets, and
+// returns the options that enable all of them. The profile is defined in the base
+// document, so it is only found while LayerBase is merged.
+func layeredProject(t *testing.T) []Option {
+	t.Helper()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	write("project.toml", `winner = "base"
+base = true
+
+[profile.debug]
+winner = "profile"
+profile = true
+`)
+	write("project.prod.toml", "winner = \"environment\"\nenvironment = true\n")
+	write("project.local.toml", "winner = \"local\"\nlocal = true\n")
+	t.Setenv("PROJECT_TOML", filepath.Join(dir, "project.toml"))
+	t.Setenv(ProfileVariable, "")
+	t.Setenv(EnvironmentVariable, "")
+
+	defaults := fstest.MapFS{"defaults.toml": {Data: []byte("winner = \"defaults\"\ndefaults = true\n")}}
+
+	return []Option{
+		WithDefaults(defaults, "defaults.toml"),
+		WithSystemConfigFile(write("system.toml", "winner = \"system\"\nsystem = true\n")),
+		WithUserConfigFile(write("user.toml", "winner = \"user\"\nuser = true\n")),
+		WithEnvironment("prod"),
+		WithProfile("debug"),
+		WithLocalOverride(true),
+	}
+}
+
+func TestLoadMergesLayersInDefaultPrecedence(t *testing.T) {
+	opts := layeredProject(t)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), opts...))
+	assert.Equal(t, "local", config["winner"])
+
+	for _, layer := range DefaultPrecedence() {
+		assert.Equal(t, true, config[string(layer)], layer)
+	}
+}
+
+func TestLoadMergesEachLayerOverTheOnesBelowIt(t *testing.T) {
+	precedence := DefaultPrecedence()
+
+	for index, top := range precedence {
+		t.Run(string(top), func(t *testing.T) {
+			opts := layeredProject(t)
+
+			opts = append(opts, WithPrecedence(precedence[index:]...))
+			if index > slices.Index(precedence, LayerBase) {
+				opts = append(opts, WithProfile(""))
+			}
+
+			var config map[string]any
+
+			require.NoError(t, Load(&config, newTestLogger(t), opts...))
+			assert.Equal(t, string(top), config["winner"])
+
+			for _, excluded := range precedence[:index] {
+				assert.NotContains(t, config, string(excluded))
+			}
+		})
+	}
+}
+
+func TestWithPrecedenceReordersLayers(t *testing.T) {
+	opts := layeredProject(t)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		append(opts, WithPrecedence(LayerDefaults, LayerBase, LayerLocal))...))
+	assert.Equal(t, "defaults", config["winner"])
+	assert.Equal(t, true, config["base"])
+	assert.Equal(t, true, config["local"])
+	assert.NotContains(t, config, "environment")
+	assert.NotContains(t, config, "system")
+}
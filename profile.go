@@ -0,0 +1,85 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// ProfileVariable names the environment variable that selects a profile.
+	ProfileVariable = "PROJECT_TOML_PROFILE"
+	// profileTable is the top-level table holding the named profiles.
+	profileTable = "profile"
+)
+
+// ErrUnknownProfile is returned when the selected profile has no [profile.<name>] table.
+var ErrUnknownProfile = errors.New("unknown configuration profile")
+
+// WithProfile selects the [profile.<name>] table to merge over the defaults instead of
+// reading the selection from ProfileVariable.
+func WithProfile(name string) Option {
+	return func(o *options) {
+		o.profile = name
+	}
+}
+
+// collectProfile removes the [profile] tables from every layer and records the selected
+// profile as the LayerProfile layer. Profiles defined in higher-precedence layers
+// override those of lower ones.
+func collectProfile(state *assembly) error {
+	profiles := make(map[string]any)
+
+	for _, layer := range state.settings.precedence {
+		table, found := state.layers[layer]
+		if !found {
+			continue
+		}
+
+		layerProfiles, hasProfiles := table[profileTable].(map[string]any)
+		if !hasProfiles {
+			continue
+		}
+
+		profiles = Merge(profiles, layerProfiles, state.settings.merge)
+		state.setLayer(layer, withoutKey(table, profileTable))
+		state.markModified()
+	}
+
+	name := selectedProfile(state.settings)
+	if name == "" {
+		return nil
+	}
+
+	selected, found := profiles[name].(map[string]any)
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+
+	state.setLayer(LayerProfile, selected)
+
+	return nil
+}
+
+// selectedProfile returns the profile chosen with WithProfile or, failing that,
+// ProfileVariable.
+func selectedProfile(settings *options) string {
+	if settings.profile != "" {
+		return settings.profile
+	}
+
+	return os.Getenv(ProfileVariable)
+}
+
+// withoutKey returns a copy of table without key.
+func withoutKey(table map[string]any, key string) map[string]any {
+	trimmed := make(map[string]any, len(table))
+
+	for name, value := range table {
+		if name != key {
+			trimmed[name] = value
+		}
+	}
+
+	return trimmed
+}
@@ -0,0 +1,58 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// profileDocument defines two profiles over shared defaults.
+const profileDocument = `[service]
+name = "tts"
+port = 8080
+
+[profile.debug.service]
+port = 9090
+
+[profile.prod.service]
+port = 443
+`
+
+func TestLoadMergesSelectedProfile(t *testing.T) {
+	localDocument(t, profileDocument)
+	t.Setenv(ProfileVariable, "debug")
+
+	var fromVariable testConfig
+
+	require.NoError(t, Load(&fromVariable, newTestLogger(t)))
+	assert.Equal(t, 9090, fromVariable.Service.Port)
+	assert.Equal(t, "tts", fromVariable.Service.Name)
+
+	var fromOption map[string]any
+
+	require.NoError(t, Load(&fromOption, newTestLogger(t), WithProfile("prod")))
+	assert.Equal(t, map[string]any{"name": "tts", "port": int64(443)}, fromOption["service"])
+	assert.NotContains(t, fromOption, profileTable)
+}
+
+func TestLoadWithoutProfileDropsProfiles(t *testing.T) {
+	localDocument(t, profileDocument)
+	t.Setenv(ProfileVariable, "")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, map[string]any{"name": "tts", "port": int64(8080)}, config["service"])
+	assert.NotContains(t, config, profileTable)
+}
+
+func TestLoadRejectsUnknownProfile(t *testing.T) {
+	localDocument(t, profileDocument)
+
+	var config testConfig
+
+	loadErr := Load(&config, newTestLogger(t), WithProfile("staging"))
+	require.ErrorIs(t, loadErr, ErrUnknownProfile)
+	assert.ErrorContains(t, loadErr, "staging")
+}
@@ -0,0 +1,63 @@
+package configurator
+
+import (
+	"io"
+	"net/http"
+)
+
+// Progress describes how much of a configuration document has been downloaded. Total is
+// the size announced by the server, or -1 when it is unknown; both count bytes as
+// transferred, before decompression.
+type Progress struct {
+	Source string
+	Bytes  int64
+	Total  int64
+}
+
+// WithProgress calls report as the body of every fetched document arrives, e.g. to show
+// progress for large documents. Returning an error aborts the download with that error,
+// which enforces a per-source transfer budget.
+func WithProgress(report func(Progress) error) Option {
+	return func(o *options) {
+		o.progress = report
+	}
+}
+
+// progressReader reports the bytes read from a response body.
+type progressReader struct {
+	io.ReadCloser
+
+	report   func(Progress) error
+	progress Progress
+}
+
+// trackProgress wraps the body of resp so that reads are reported for source.
+func (o *options) trackProgress(source string, resp *http.Response) {
+	if o.progress == nil {
+		return
+	}
+
+	resp.Body = &progressReader{
+		ReadCloser: resp.Body,
+		report:     o.progress,
+		progress:   Progress{Source: source, Total: resp.ContentLength},
+	}
+}
+
+// Read reads from the body and reports the new total, failing with the error the
+// callback returns.
+func (r *progressReader) Read(buffer []byte) (int, error) {
+	read, readErr := r.ReadCloser.Read(buffer)
+	if read == 0 {
+		return read, readErr
+	}
+
+	r.progress.Bytes += int64(read)
+
+	reportErr := r.report(r.progress)
+	if reportErr != nil {
+		return read, reportErr
+	}
+
+	return read, readErr
+}
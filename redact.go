@@ -0,0 +1,174 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// RedactedMask replaces the values of non-empty secret string fields in redacted output.
+const RedactedMask = "[REDACTED]"
+
+// RedactedConfig formats a configuration with the fields tagged secret:"true" masked,
+// so services can log their configuration at startup without leaking credentials.
+type RedactedConfig struct {
+	masked any
+}
+
+// Redacted returns config wrapped for logging: its String, GoString, and MarshalJSON
+// output show non-empty secret strings as RedactedMask and other secret fields as their
+// zero value. config itself is left untouched.
+func Redacted(config any) RedactedConfig {
+	value := reflect.ValueOf(config)
+	if !value.IsValid() {
+		return RedactedConfig{}
+	}
+
+	return RedactedConfig{masked: maskSecrets(value).Interface()}
+}
+
+// String formats the masked configuration like %+v.
+func (r RedactedConfig) String() string {
+	return fmt.Sprintf("%+v", r.masked)
+}
+
+// GoString formats the masked configuration like %#v.
+func (r RedactedConfig) GoString() string {
+	return fmt.Sprintf("%#v", r.masked)
+}
+
+// MarshalJSON encodes the masked configuration.
+func (r RedactedConfig) MarshalJSON() ([]byte, error) {
+	encoded, marshalErr := json.Marshal(r.masked)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to encode redacted configuration: %w", marshalErr)
+	}
+
+	return encoded, nil
+}
+
+// copyKey identifies a pointer, map, or slice already copied, so a value reached twice
+// is copied once and cycles end.
+type copyKey struct {
+	pointer uintptr
+	length  int
+	typ     reflect.Type
+}
+
+// secretMasker makes masked deep copies, remembering the copy of every pointer, map, and
+// slice it has visited.
+type secretMasker struct {
+	copies map[copyKey]reflect.Value
+}
+
+// maskSecrets returns a deep copy of value with its secret fields masked. Shared and
+// cyclic references are preserved in the copy.
+func maskSecrets(value reflect.Value) reflect.Value {
+	masker := &secretMasker{copies: make(map[copyKey]reflect.Value)}
+
+	return masker.mask(value)
+}
+
+// mask returns a deep copy of value with its secret fields masked.
+func (m *secretMasker) mask(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+
+		key := copyKey{pointer: value.Pointer(), typ: value.Type()}
+		if masked, visited := m.copies[key]; visited {
+			return masked
+		}
+
+		masked := reflect.New(value.Type().Elem())
+		m.copies[key] = masked
+		masked.Elem().Set(m.mask(value.Elem()))
+
+		return masked
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+
+		masked := reflect.New(value.Type()).Elem()
+		masked.Set(m.mask(value.Elem()))
+
+		return masked
+	case reflect.Struct:
+		return m.maskStruct(value)
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+
+		key := copyKey{pointer: value.Pointer(), length: value.Len(), typ: value.Type()}
+		if masked, visited := m.copies[key]; visited {
+			return masked
+		}
+
+		masked := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		m.copies[key] = masked
+
+		for index := range value.Len() {
+			masked.Index(index).Set(m.mask(value.Index(index)))
+		}
+
+		return masked
+	case reflect.Array:
+		masked := reflect.New(value.Type()).Elem()
+		for index := range value.Len() {
+			masked.Index(index).Set(m.mask(value.Index(index)))
+		}
+
+		return masked
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+
+		key := copyKey{pointer: value.Pointer(), typ: value.Type()}
+		if masked, visited := m.copies[key]; visited {
+			return masked
+		}
+
+		masked := reflect.MakeMapWithSize(value.Type(), value.Len())
+		m.copies[key] = masked
+
+		for iterator := value.MapRange(); iterator.Next(); {
+			masked.SetMapIndex(iterator.Key(), m.mask(iterator.Value()))
+		}
+
+		return masked
+	default:
+		return value
+	}
+}
+
+// maskStruct returns a copy of the struct value with its secret fields masked and its
+// other exported fields deep-copied.
+func (m *secretMasker) maskStruct(value reflect.Value) reflect.Value {
+	masked := reflect.New(value.Type()).Elem()
+	masked.Set(value)
+
+	for index := range value.NumField() {
+		field := value.Type().Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		target := masked.Field(index)
+
+		switch {
+		case field.Tag.Get(secretTag) != "true":
+			target.Set(m.mask(value.Field(index)))
+		case target.Kind() == reflect.String && target.Len() > 0:
+			target.SetString(RedactedMask)
+		default:
+			target.SetZero()
+		}
+	}
+
+	return masked
+}
@@ -0,0 +1,72 @@
+package configurator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redactNode is a configuration that can refer to itself.
+type redactNode struct {
+	Name     string            `json:"name"`
+	Password string            `json:"password" secret:"true"`
+	Tokens   map[string]string `json:"tokens"   secret:"true"`
+	Next     *redactNode       `json:"-"`
+	Children []redactNode      `json:"children"`
+}
+
+func TestRedactedMasksSecretFields(t *testing.T) {
+	t.Parallel()
+
+	config := redactNode{
+		Name:     "tts",
+		Password: "hunter2",
+		Tokens:   map[string]string{"api": "token"},
+		Children: []redactNode{{Name: "child", Password: "child-secret"}, {Name: "empty"}},
+	}
+
+	redacted := Redacted(&config)
+
+	assert.NotContains(t, redacted.String(), "hunter2")
+	assert.NotContains(t, redacted.GoString(), "child-secret")
+
+	encoded, marshalErr := json.Marshal(redacted)
+	require.NoError(t, marshalErr)
+	assert.JSONEq(t, `{"name":"tts","password":"[REDACTED]","tokens":null,"children":[
+		{"name":"child","password":"[REDACTED]","tokens":null,"children":null},
+		{"name":"empty","password":"","tokens":null,"children":null}]}`, string(encoded))
+
+	assert.Equal(t, "hunter2", config.Password)
+	assert.Equal(t, map[string]string{"api": "token"}, config.Tokens)
+	assert.Equal(t, "child-secret", config.Children[0].Password)
+}
+
+func TestRedactedSurvivesCycles(t *testing.T) {
+	t.Parallel()
+
+	first := &redactNode{Name: "first", Password: "one"}
+	second := &redactNode{Name: "second", Password: "two", Next: first}
+	first.Next = second
+
+	redacted := Redacted(first)
+	assert.NotContains(t, redacted.String(), "one")
+
+	masked, _ := redacted.masked.(*redactNode)
+	require.NotNil(t, masked)
+	assert.Equal(t, RedactedMask, masked.Password)
+	assert.Equal(t, RedactedMask, masked.Next.Password)
+	assert.Same(t, masked, masked.Next.Next)
+	assert.NotSame(t, first, masked)
+
+	looped := map[string]any{"name": "loop"}
+	looped["self"] = looped
+
+	maskedMap, _ := Redacted(looped).masked.(map[string]any)
+	require.NotNil(t, maskedMap)
+	assert.Equal(t, "loop", maskedMap["name"])
+
+	self, _ := maskedMap["self"].(map[string]any)
+	assert.Equal(t, "loop", self["name"])
+}
@@ -0,0 +1,161 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUntrustedReference is returned when a document that may not name secrets contains
+// a vault:// or secretref:// reference.
+var ErrUntrustedReference = errors.New("secret reference in untrusted document")
+
+// checkReferences fails with ErrUntrustedReference when table, read from source, holds
+// a secret reference although source may not name secrets. Only local files and
+// documents served by the hosts that receive credentials may, so a third-party include
+// cannot have local secrets resolved into settings it controls.
+func checkReferences(settings *options, source string, table map[string]any) error {
+	unpinned, _ := splitPin(source)
+	if _, isLocal := localPath(unpinned); isLocal || settings.sendsCredentials(unpinned) {
+		return nil
+	}
+
+	if path, found := findReference("", table); found {
+		return fmt.Errorf("%w: %s sets %s", ErrUntrustedReference, unpinned, path)
+	}
+
+	return nil
+}
+
+// findReference returns the path of the first secret reference within value.
+func findReference(path string, value any) (string, bool) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, nested := range typed {
+			if found, isReference := findReference(joinKey(path, key), nested); isReference {
+				return found, true
+			}
+		}
+	case []any:
+		for index, element := range typed {
+			if found, isReference := findReference(fmt.Sprintf("%s[%d]", path, index), element); isReference {
+				return found, true
+			}
+		}
+	case string:
+		return path, strings.HasPrefix(typed, vaultPrefix) || strings.HasPrefix(typed, secretRefPrefix)
+	}
+
+	return "", false
+}
+
+// resolveReferences returns root with every string value that resolve recognizes as a
+// reference replaced by the value it resolves to. The boolean result reports whether
+// any reference was found. Distinct strings are resolved concurrently on at most limit
+// goroutines, so resolve must be safe for concurrent use, and every failing reference
+// is reported.
+func resolveReferences(root map[string]any, resolve func(string) (any, bool, error), limit int) (map[string]any, bool, error) {
+	resolver := &referenceResolver{resolutions: make(map[string]*resolution)}
+	resolver.collect(root)
+
+	values := make([]string, 0, len(resolver.resolutions))
+	for value := range resolver.resolutions {
+		values = append(values, value)
+	}
+
+	runParallel(len(values), limit, func(index int) {
+		result := resolver.resolutions[values[index]]
+		result.value, result.isReference, result.err = resolve(values[index])
+	})
+
+	resolved := resolver.value("", root)
+	if len(resolver.errs) > 0 {
+		slices.SortFunc(resolver.errs, func(a, b *SourceError) int {
+			return strings.Compare(a.Source, b.Source)
+		})
+
+		errs := make([]error, len(resolver.errs))
+		for index, sourceErr := range resolver.errs {
+			errs[index] = sourceErr
+		}
+
+		return nil, false, errors.Join(errs...)
+	}
+
+	table, _ := resolved.(map[string]any)
+
+	return table, resolver.found, nil
+}
+
+// resolution is the outcome of resolving one distinct string.
+type resolution struct {
+	value       any
+	isReference bool
+	err         error
+}
+
+// referenceResolver replaces references within a table with their resolutions. found
+// records whether any reference was seen and errs the failed ones.
+type referenceResolver struct {
+	resolutions map[string]*resolution
+	found       bool
+	errs        []*SourceError
+}
+
+// collect records every distinct string within value.
+func (r *referenceResolver) collect(value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for _, nested := range typed {
+			r.collect(nested)
+		}
+	case []any:
+		for _, element := range typed {
+			r.collect(element)
+		}
+	case string:
+		if _, seen := r.resolutions[typed]; !seen {
+			r.resolutions[typed] = &resolution{}
+		}
+	}
+}
+
+// value returns a copy of value with the references within it resolved.
+func (r *referenceResolver) value(path string, value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		resolved := make(map[string]any, len(typed))
+
+		for key, nested := range typed {
+			resolved[key] = r.value(joinKey(path, key), nested)
+		}
+
+		return resolved
+	case []any:
+		resolved := make([]any, len(typed))
+
+		for index, element := range typed {
+			resolved[index] = r.value(fmt.Sprintf("%s[%d]", path, index), element)
+		}
+
+		return resolved
+	case string:
+		result := r.resolutions[typed]
+		if !result.isReference {
+			return typed
+		}
+
+		r.found = true
+
+		if result.err != nil {
+			r.errs = append(r.errs, &SourceError{Source: path, Err: result.err})
+
+			return typed
+		}
+
+		return result.value
+	default:
+		return value
+	}
+}
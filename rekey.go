@@ -0,0 +1,56 @@
+package configurator
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// encryptedLiteral matches a quoted enc: value in a TOML document, capturing its base64
+// payload.
+var encryptedLiteral = regexp.MustCompile(`(["'])` + encryptedValuePrefix + `([A-Za-z0-9+/]+={0,2})(["'])`)
+
+// Rekey re-encrypts every enc: value in the TOML document content from oldKey to newKey
+// and returns the updated document. Everything else, including comments and layout, is
+// left as it was, so key rotation needs no manual editing.
+func Rekey(content, oldKey, newKey []byte) ([]byte, error) {
+	oldCipher, oldErr := newSecretCipher(oldKey)
+	if oldErr != nil {
+		return nil, fmt.Errorf("old key: %w", oldErr)
+	}
+
+	_, newErr := newSecretCipher(newKey)
+	if newErr != nil {
+		return nil, fmt.Errorf("new key: %w", newErr)
+	}
+
+	var rekeyErr error
+
+	rekeyed := encryptedLiteral.ReplaceAllFunc(content, func(literal []byte) []byte {
+		if rekeyErr != nil {
+			return literal
+		}
+
+		parts := encryptedLiteral.FindSubmatch(literal)
+
+		plaintext, openErr := openSealed(oldCipher, string(parts[2]))
+		if openErr != nil {
+			rekeyErr = openErr
+
+			return literal
+		}
+
+		encrypted, encryptErr := EncryptValue(plaintext, newKey)
+		if encryptErr != nil {
+			rekeyErr = encryptErr
+
+			return literal
+		}
+
+		return []byte(string(parts[1]) + encrypted + string(parts[3]))
+	})
+	if rekeyErr != nil {
+		return nil, rekeyErr
+	}
+
+	return rekeyed, nil
+}
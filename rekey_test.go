@@ -0,0 +1,91 @@
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rekeyDocument lays out enc: values among comments, alignment, and quoting styles
+// that Rekey must keep.
+const rekeyDocument = `# Production settings.
+# Owner: platform team
+
+[service]
+name     = "tts"       # aligned
+password = %q   # rotated yearly
+
+[[nested]]
+secret = '%s'
+
+keys = [
+    %q,  # first
+    "clear",
+]
+`
+
+func TestRekeyPreservesCommentsAndLayout(t *testing.T) {
+	t.Parallel()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	content := fmt.Sprintf(rekeyDocument,
+		encrypt(t, "hunter2", oldKey), encrypt(t, "deep", oldKey), encrypt(t, "first", oldKey))
+
+	rekeyed, rekeyErr := Rekey([]byte(content), oldKey, newKey)
+	require.NoError(t, rekeyErr)
+
+	values := encryptedLiteral.FindAllStringSubmatch(string(rekeyed), -1)
+	require.Len(t, values, 3)
+
+	skeleton := func(document string) string {
+		return encryptedLiteral.ReplaceAllString(document, "${1}enc:VALUE${3}")
+	}
+	assert.Equal(t, skeleton(content), skeleton(string(rekeyed)))
+	assert.NotContains(t, string(rekeyed), encryptedLiteral.FindStringSubmatch(content)[2])
+
+	newCipher, cipherErr := newSecretCipher(newKey)
+	require.NoError(t, cipherErr)
+
+	for index, want := range []string{"hunter2", "deep", "first"} {
+		plaintext, openErr := openSealed(newCipher, values[index][2])
+		require.NoError(t, openErr)
+		assert.Equal(t, want, plaintext)
+	}
+
+	oldCipher, cipherErr := newSecretCipher(oldKey)
+	require.NoError(t, cipherErr)
+
+	_, openErr := openSealed(oldCipher, values[0][2])
+	require.ErrorIs(t, openErr, ErrInvalidSecret)
+}
+
+func TestRekeyLeavesDocumentsWithoutEncryptedValues(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("# nothing secret\n[service]\nname = \"enc\"\nnote = 'enc: is the prefix'\n")
+
+	rekeyed, rekeyErr := Rekey(content, bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, rekeyErr)
+	assert.Equal(t, content, rekeyed)
+}
+
+func TestRekeyRejectsWrongKeys(t *testing.T) {
+	t.Parallel()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	content := []byte(fmt.Sprintf("password = %q\n", encrypt(t, "hunter2", oldKey)))
+
+	_, wrongErr := Rekey(content, bytes.Repeat([]byte{3}, 32), bytes.Repeat([]byte{2}, 32))
+	require.ErrorIs(t, wrongErr, ErrInvalidSecret)
+
+	_, oldSizeErr := Rekey(content, []byte("short"), bytes.Repeat([]byte{2}, 32))
+	require.ErrorContains(t, oldSizeErr, "old key")
+
+	_, newSizeErr := Rekey(content, oldKey, []byte("short"))
+	require.ErrorContains(t, newSizeErr, "new key")
+}
@@ -0,0 +1,162 @@
+package configurator
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// RetriesVariable names the environment variable holding the number of attempts made
+	// for each fetch, used when WithRetry is not given.
+	RetriesVariable = "PROJECT_TOML_RETRIES"
+	// DefaultRetryAttempts is the number of attempts made for each fetch when none is
+	// configured, so a single failed request does not fail the load.
+	DefaultRetryAttempts = 3
+	// DefaultRetryBaseDelay is the delay before the first retry when none is configured.
+	DefaultRetryBaseDelay = 200 * time.Millisecond
+	// DefaultRetryMaxDelay caps the delay between retries when no cap is configured.
+	DefaultRetryMaxDelay = 5 * time.Second
+	// DefaultRetryJitter is the fraction of each delay randomized when none is configured.
+	DefaultRetryJitter = 0.2
+)
+
+// RetryPolicy describes how fetches are retried after transient failures: network errors,
+// timeouts, 429 Too Many Requests, and 5xx responses. A Retry-After header lengthens
+// the delay before the next attempt, up to MaxDelay; a longer one ends the retries.
+// Attempts counts the first try, so 1 disables retries. The delay starts at BaseDelay
+// and doubles with every retry up to MaxDelay; Jitter, between 0 and 1, is the fraction
+// of each delay that is randomized so that many services do not retry in lockstep. Zero
+// attempts, durations, and jitter take the defaults.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    float64
+}
+
+// WithRetry retries fetches that fail transiently according to policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// defaultRetryPolicy returns the policy with the number of attempts from RetriesVariable,
+// or DefaultRetryAttempts when it is unset.
+func defaultRetryPolicy() RetryPolicy {
+	attempts, _ := strconv.Atoi(os.Getenv(RetriesVariable))
+
+	return RetryPolicy{Attempts: attempts}
+}
+
+// delay returns how long to wait before retrying after the given failed attempt,
+// counting from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	base, limit, jitter := p.BaseDelay, p.maxDelay(), p.Jitter
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+
+	if jitter <= 0 {
+		jitter = DefaultRetryJitter
+	}
+
+	delay := limit
+	if shift := attempt - 1; shift < 32 && base<<shift < limit {
+		delay = base << shift
+	}
+
+	return delay - time.Duration(float64(delay)*min(jitter, 1)*rand.Float64())
+}
+
+// attempts returns the number of attempts made for each fetch, Attempts or its default.
+func (p RetryPolicy) attempts() int {
+	if p.Attempts <= 0 {
+		return DefaultRetryAttempts
+	}
+
+	return p.Attempts
+}
+
+// maxDelay returns the longest wait between attempts, MaxDelay or its default.
+func (p RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return DefaultRetryMaxDelay
+	}
+
+	return p.MaxDelay
+}
+
+// retryAfterError is a failed response whose Retry-After header asked the client to wait.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+// Error returns the message of the underlying error.
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// withRetryAfter wraps err with the wait requested by the Retry-After header of a 429 or
+// 503 response, returning err unchanged when there is none.
+func withRetryAfter(resp *http.Response, err error) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return err
+	}
+
+	after, found := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !found {
+		return err
+	}
+
+	return &retryAfterError{err: err, after: after}
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, parseErr := strconv.Atoi(value); parseErr == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	date, parseErr := http.ParseTime(value)
+	if parseErr != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// retryAfter returns the wait requested by the server that failed err, if any.
+func retryAfter(err error) time.Duration {
+	var waitErr *retryAfterError
+	if errors.As(err, &waitErr) {
+		return waitErr.after
+	}
+
+	return 0
+}
+
+// isTransientError reports whether a failed request may succeed when retried, which is
+// the case unless a URL check, the redirect policy, or the internal address block
+// refused it.
+func isTransientError(err error) bool {
+	return !errors.Is(err, ErrHostNotAllowed) &&
+		!errors.Is(err, ErrInsecureURL) &&
+		!errors.Is(err, ErrTooManyRedirects) &&
+		!errors.Is(err, ErrRedirectNotAllowed) &&
+		!errors.Is(err, ErrInternalAddress)
+}
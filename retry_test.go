@@ -0,0 +1,118 @@
+package configurator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throttlingServer answers the first failures requests with 429 Too Many Requests and
+// the given Retry-After, and the rest with a document. It counts the requests made.
+func throttlingServer(t *testing.T, failures int64, retryAfter string) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) <= failures {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("[service]\nname = \"tts\"\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+
+	after, found := parseRetryAfter("3", now)
+	require.True(t, found)
+	assert.Equal(t, 3*time.Second, after)
+
+	after, found = parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	require.True(t, found)
+	assert.Equal(t, time.Minute, after)
+
+	_, found = parseRetryAfter("soon", now)
+	assert.False(t, found)
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	server, requests := throttlingServer(t, 1, "1")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	started := time.Now()
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithRetry(RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second})))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, int64(2), requests.Load())
+	assert.GreaterOrEqual(t, time.Since(started), time.Second)
+}
+
+func TestRetryAfterBeyondMaxDelayEndsRetries(t *testing.T) {
+	server, requests := throttlingServer(t, 1, "3600")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config testConfig
+
+	started := time.Now()
+
+	require.Error(t, Load(&config, newTestLogger(t),
+		WithRetry(RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second})))
+	assert.Equal(t, int64(1), requests.Load())
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	server, requests := throttlingServer(t, 1, "5")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var config testConfig
+
+	started := time.Now()
+
+	loadErr := Load(&config, newTestLogger(t), WithContext(ctx),
+		WithRetry(RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Second}))
+	require.ErrorIs(t, loadErr, context.DeadlineExceeded)
+	assert.Equal(t, int64(1), requests.Load())
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestRetryIsOnByDefault(t *testing.T) {
+	server, requests := throttlingServer(t, 1, "0")
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+	t.Setenv(RetriesVariable, "")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, "tts", config.Service.Name)
+	assert.Equal(t, int64(2), requests.Load())
+
+	disabled, disabledRequests := throttlingServer(t, 1, "0")
+	t.Setenv("PROJECT_TOML", disabled.URL+"/project.toml")
+	t.Setenv(RetriesVariable, "1")
+
+	require.Error(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, int64(1), disabledRequests.Load())
+}
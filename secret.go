@@ -0,0 +1,253 @@
+package configurator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	// SecretKeyVariable names the environment variable holding the base64-encoded
+	// AES-256 key that decrypts enc: values.
+	SecretKeyVariable = "PROJECT_TOML_SECRET_KEY"
+	// secretTag marks the struct fields whose enc: values are decrypted.
+	secretTag = "secret"
+	// encryptedValuePrefix marks a value encrypted with EncryptValue.
+	encryptedValuePrefix = "enc:"
+)
+
+var (
+	// ErrSecretKeyNotSet is returned when an enc: value is loaded but no key is configured.
+	ErrSecretKeyNotSet = errors.New("secret key not set")
+	// ErrInvalidSecret is returned when an enc: or kms: value cannot be decrypted.
+	ErrInvalidSecret = errors.New("invalid encrypted value")
+)
+
+// WithSecretKey sets the AES-256 key that decrypts enc: values instead of reading it
+// from SecretKeyVariable.
+func WithSecretKey(key []byte) Option {
+	return func(o *options) {
+		o.secretKey = key
+	}
+}
+
+// EncryptValue encrypts plaintext with the AES-256 key using AES-GCM and returns an
+// enc: value to store in project.toml. Load decrypts it into fields tagged
+// secret:"true".
+func EncryptValue(plaintext string, key []byte) (string, error) {
+	aead, aeadErr := newSecretCipher(key)
+	if aeadErr != nil {
+		return "", aeadErr
+	}
+
+	sealed, sealErr := sealValue(aead, []byte(plaintext))
+	if sealErr != nil {
+		return "", sealErr
+	}
+
+	return encryptedValuePrefix + sealed, nil
+}
+
+// sealValue encrypts plaintext with aead under a random nonce and returns the base64
+// nonce and ciphertext that openSealed decrypts.
+func sealValue(aead cipher.AEAD, plaintext []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+
+	_, randErr := rand.Read(nonce)
+	if randErr != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", randErr)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// revealSecrets decrypts the enc: and kms: values of the string fields tagged
+// secret:"true" in the struct target points to, at any depth, including the strings held
+// in the maps, slices, and interfaces of such fields. Values stay encrypted everywhere
+// else, including the raw document.
+func revealSecrets(target any, settings *options) error {
+	revealer := &secretRevealer{settings: settings}
+
+	return revealer.value("", reflect.ValueOf(target), false)
+}
+
+// secretRevealer decrypts secret fields, loading the key on first use.
+type secretRevealer struct {
+	settings *options
+	aead     cipher.AEAD
+}
+
+// value decrypts the secret fields within value. secret is true when value is a field
+// tagged secret:"true".
+func (r *secretRevealer) value(path string, value reflect.Value, secret bool) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+
+		return r.value(path, value.Elem(), secret)
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		if !value.CanSet() {
+			return r.value(path, value.Elem(), secret)
+		}
+
+		return r.replace(path, value.Elem(), secret, value.Set)
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+
+			elementErr := r.replace(joinKey(path, fmt.Sprint(key.Interface())), iter.Value(), secret, func(element reflect.Value) {
+				value.SetMapIndex(key, element)
+			})
+			if elementErr != nil {
+				return elementErr
+			}
+		}
+	case reflect.Struct:
+		for index := range value.NumField() {
+			field := value.Type().Field(index)
+			if !field.IsExported() {
+				continue
+			}
+
+			fieldErr := r.value(joinKey(path, field.Name), value.Field(index), field.Tag.Get(secretTag) == "true")
+			if fieldErr != nil {
+				return fieldErr
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for index := range value.Len() {
+			elementErr := r.value(fmt.Sprintf("%s[%d]", path, index), value.Index(index), secret)
+			if elementErr != nil {
+				return elementErr
+			}
+		}
+	case reflect.String:
+		if secret && value.CanSet() {
+			return r.reveal(path, value)
+		}
+	}
+
+	return nil
+}
+
+// replace decrypts the secret fields within a copy of element, which cannot be set in
+// place, such as a map value or the value inside an interface, and stores the copy.
+func (r *secretRevealer) replace(path string, element reflect.Value, secret bool, store func(reflect.Value)) error {
+	settable := reflect.New(element.Type()).Elem()
+	settable.Set(element)
+
+	elementErr := r.value(path, settable, secret)
+	if elementErr != nil {
+		return elementErr
+	}
+
+	store(settable)
+
+	return nil
+}
+
+// reveal replaces the enc: or kms: value of field with its plaintext.
+func (r *secretRevealer) reveal(path string, field reflect.Value) error {
+	var (
+		plaintext string
+		openErr   error
+	)
+
+	if encoded, encrypted := strings.CutPrefix(field.String(), encryptedValuePrefix); encrypted {
+		plaintext, openErr = r.open(encoded)
+	} else if encoded, enveloped := strings.CutPrefix(field.String(), kmsValuePrefix); enveloped {
+		plaintext, openErr = openKMSValue(r.settings.loadContext, r.settings, encoded)
+	} else {
+		return nil
+	}
+
+	if openErr != nil {
+		return fmt.Errorf("%s: %w", path, openErr)
+	}
+
+	field.SetString(plaintext)
+
+	return nil
+}
+
+// open decrypts the part of an enc: value after the prefix with the configured key.
+func (r *secretRevealer) open(encoded string) (string, error) {
+	if r.aead == nil {
+		aead, keyErr := secretCipher(r.settings)
+		if keyErr != nil {
+			return "", keyErr
+		}
+
+		r.aead = aead
+	}
+
+	return openSealed(r.aead, encoded)
+}
+
+// secretCipher returns the AES-GCM cipher for the key configured in settings.
+func secretCipher(settings *options) (cipher.AEAD, error) {
+	key := settings.secretKey
+	if key == nil {
+		encoded := os.Getenv(SecretKeyVariable)
+		if encoded == "" {
+			return nil, ErrSecretKeyNotSet
+		}
+
+		decoded, decodeErr := base64.StdEncoding.DecodeString(encoded)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", SecretKeyVariable, decodeErr)
+		}
+
+		key = decoded
+	}
+
+	return newSecretCipher(key)
+}
+
+// newSecretCipher returns an AES-GCM cipher for key.
+func newSecretCipher(key []byte) (cipher.AEAD, error) {
+	block, blockErr := aes.NewCipher(key)
+	if blockErr != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", blockErr)
+	}
+
+	aead, gcmErr := cipher.NewGCM(block)
+	if gcmErr != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", gcmErr)
+	}
+
+	return aead, nil
+}
+
+// openSealed decodes and decrypts the base64 nonce and ciphertext produced by
+// EncryptValue.
+func openSealed(aead cipher.AEAD, encoded string) (string, error) {
+	sealed, decodeErr := base64.StdEncoding.DecodeString(encoded)
+	if decodeErr != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, openErr := aead.Open(nil, nonce, ciphertext, nil)
+	if openErr != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidSecret, openErr)
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,104 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secretConfig has secret fields of every shape Load decrypts into.
+type secretConfig struct {
+	Password string            `toml:"password" secret:"true"`
+	Plain    string            `toml:"plain"`
+	Tokens   map[string]string `toml:"tokens"   secret:"true"`
+	Keys     []string          `toml:"keys"     secret:"true"`
+	Extra    any               `toml:"extra"    secret:"true"`
+	Nested   []struct {
+		Secret string `toml:"secret" secret:"true"`
+	} `toml:"nested"`
+}
+
+// encrypt returns the enc: value of plaintext under key.
+func encrypt(t *testing.T, plaintext string, key []byte) string {
+	t.Helper()
+
+	encrypted, encryptErr := EncryptValue(plaintext, key)
+	require.NoError(t, encryptErr)
+	require.True(t, strings.HasPrefix(encrypted, encryptedValuePrefix))
+
+	return encrypted
+}
+
+func TestLoadDecryptsEncryptedValues(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plain := encrypt(t, "stays encrypted", key)
+
+	localDocument(t, fmt.Sprintf(`password = %q
+plain = %q
+keys = [%q, "clear"]
+extra = { token = %q }
+
+[tokens]
+api = %q
+
+[[nested]]
+secret = %q
+`, encrypt(t, "hunter2", key), plain, encrypt(t, "first", key), encrypt(t, "inner", key),
+		encrypt(t, "api-token", key), encrypt(t, "deep", key)))
+	t.Setenv(SecretKeyVariable, base64.StdEncoding.EncodeToString(key))
+
+	var config secretConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, "hunter2", config.Password)
+	assert.Equal(t, plain, config.Plain)
+	assert.Equal(t, map[string]string{"api": "api-token"}, config.Tokens)
+	assert.Equal(t, []string{"first", "clear"}, config.Keys)
+	assert.Equal(t, map[string]any{"token": "inner"}, config.Extra)
+	require.Len(t, config.Nested, 1)
+	assert.Equal(t, "deep", config.Nested[0].Secret)
+}
+
+func TestLoadRejectsUndecryptableValues(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	encrypted := encrypt(t, "hunter2", key)
+	sealed, decodeErr := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, encryptedValuePrefix))
+	require.NoError(t, decodeErr)
+
+	sealed[len(sealed)-1] ^= 1
+	tampered := encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := map[string]struct {
+		content string
+		key     []byte
+		want    error
+	}{
+		"wrong key": {
+			content: fmt.Sprintf("password = %q\n", encrypted), key: bytes.Repeat([]byte{8}, 32), want: ErrInvalidSecret,
+		},
+		"tampered ciphertext": {content: fmt.Sprintf("password = %q\n", tampered), key: key, want: ErrInvalidSecret},
+		"malformed value":     {content: "password = \"enc:not base64!\"\n", key: key, want: ErrInvalidSecret},
+		"missing key":         {content: fmt.Sprintf("password = %q\n", encrypted), want: ErrSecretKeyNotSet},
+		"wrong key in a map": {
+			content: fmt.Sprintf("[tokens]\napi = %q\n", encrypted), key: bytes.Repeat([]byte{8}, 32), want: ErrInvalidSecret,
+		},
+		"tampered in a slice":  {content: fmt.Sprintf("keys = [%q]\n", tampered), key: key, want: ErrInvalidSecret},
+		"missing key in a map": {content: fmt.Sprintf("[tokens]\napi = %q\n", encrypted), want: ErrSecretKeyNotSet},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(SecretKeyVariable, "")
+			localDocument(t, test.content)
+
+			var config secretConfig
+
+			require.ErrorIs(t, Load(&config, newTestLogger(t), WithSecretKey(test.key)), test.want)
+		})
+	}
+}
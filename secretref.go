@@ -0,0 +1,83 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+// secretRefPrefix starts a value that refers to a secret held by a SecretResolver.
+const secretRefPrefix = "secretref://"
+
+// ErrUnknownSecretProvider is returned when a secretref:// reference names a provider
+// that has no registered SecretResolver.
+var ErrUnknownSecretProvider = errors.New("unknown secret provider")
+
+// SecretResolver looks up secrets in a backend of the caller's choosing. path is the
+// part of a secretref://<provider>/<path> reference after the provider.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, path string) (string, error)
+}
+
+// WithSecretResolver resolves secretref://<provider>/<path> values with resolver.
+func WithSecretResolver(provider string, resolver SecretResolver) Option {
+	return func(o *options) {
+		if o.secretResolvers == nil {
+			o.secretResolvers = make(map[string]SecretResolver)
+		}
+
+		o.secretResolvers[provider] = resolver
+	}
+}
+
+// secretReferences resolves the vault:// and secretref:// references of one load. It is
+// safe for concurrent use; resolveReferences looks each distinct reference up once.
+type secretReferences struct {
+	vault     *vaultResolver
+	resolvers map[string]SecretResolver
+	ctx       context.Context
+	timeout   time.Duration
+}
+
+// newSecretReferences returns the reference resolver for a load with settings.
+func newSecretReferences(settings *options, logger *logger.Logger) *secretReferences {
+	return &secretReferences{
+		vault:     newVaultResolver(settings, logger),
+		resolvers: settings.secretResolvers,
+		ctx:       settings.loadContext,
+		timeout:   settings.urlTimeout,
+	}
+}
+
+// resolve returns the secret value references. The boolean result is false when value
+// is not a secret reference.
+func (s *secretReferences) resolve(value string) (any, bool, error) {
+	reference, isSecretRef := strings.CutPrefix(value, secretRefPrefix)
+	if !isSecretRef {
+		return s.vault.resolve(value)
+	}
+
+	provider, path, hasPath := strings.Cut(reference, "/")
+	if !hasPath || provider == "" || path == "" {
+		return nil, true, fmt.Errorf("%w: %s", ErrInvalidSecretReference, value)
+	}
+
+	resolver, registered := s.resolvers[provider]
+	if !registered {
+		return nil, true, fmt.Errorf("%w: %s", ErrUnknownSecretProvider, provider)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+
+	secret, resolveErr := resolver.ResolveSecret(ctx, path)
+	if resolveErr != nil {
+		return nil, true, fmt.Errorf("failed to resolve %s: %w", value, resolveErr)
+	}
+
+	return secret, true, nil
+}
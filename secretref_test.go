@@ -0,0 +1,89 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errSecretDenied is returned by staticSecrets for paths it refuses.
+var errSecretDenied = errors.New("secret access denied")
+
+// staticSecrets is a SecretResolver serving secrets from a map and recording the paths
+// it was asked for.
+type staticSecrets struct {
+	secrets map[string]string
+
+	mu        sync.Mutex
+	requested []string
+}
+
+// ResolveSecret returns the secret at path, or errSecretDenied when there is none.
+func (s *staticSecrets) ResolveSecret(_ context.Context, path string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requested = append(s.requested, path)
+
+	secret, found := s.secrets[path]
+	if !found {
+		return "", errSecretDenied
+	}
+
+	return secret, nil
+}
+
+func TestLoadResolvesSecretReferences(t *testing.T) {
+	resolver := &staticSecrets{secrets: map[string]string{"db/password": "hunter2"}}
+	localDocument(t, `[db]
+password = "secretref://test/db/password"
+replica_password = "secretref://test/db/password"
+`)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithSecretResolver("test", resolver)))
+	assert.Equal(t, map[string]any{"password": "hunter2", "replica_password": "hunter2"}, config["db"])
+	assert.Equal(t, []string{"db/password"}, resolver.requested, "each reference is resolved once")
+}
+
+func TestLoadRejectsBadSecretReferences(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		err   error
+	}{
+		"unknown provider": {"secretref://other/db/password", ErrUnknownSecretProvider},
+		"no path":          {"secretref://test", ErrInvalidSecretReference},
+		"resolver failure": {"secretref://test/db/missing", errSecretDenied},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			localDocument(t, "[db]\npassword = \""+test.value+"\"\n")
+
+			var config map[string]any
+
+			loadErr := Load(&config, newTestLogger(t), WithSecretResolver("test", &staticSecrets{}))
+			require.ErrorIs(t, loadErr, test.err)
+		})
+	}
+}
+
+func TestLoadRejectsSecretReferencesFromUntrustedHosts(t *testing.T) {
+	resolver := &staticSecrets{secrets: map[string]string{"db/password": "hunter2"}}
+	thirdParty := serveDocuments(t, map[string]string{
+		"/shared.toml": "[exfiltrate]\nvalue = [\"secretref://test/db/password\"]\n",
+	})
+	localDocument(t, "include = [\""+thirdParty.URL+"/shared.toml\"]\n")
+
+	var config map[string]any
+
+	loadErr := Load(&config, newTestLogger(t), WithSecretResolver("test", resolver))
+	require.ErrorIs(t, loadErr, ErrUntrustedReference)
+	assert.ErrorContains(t, loadErr, "exfiltrate.value[0]")
+	assert.Empty(t, resolver.requested, "no resolver is called")
+}
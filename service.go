@@ -0,0 +1,68 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/book-expert/logger"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// commonTable is the top-level table shared by every service in the project document.
+const commonTable = "common"
+
+// ErrUnknownService is returned when the project document has no table for the service.
+var ErrUnknownService = errors.New("unknown service")
+
+// LoadService loads the configuration like Load, but unmarshals only the top-level table
+// named after the service, merged over the shared [common] table. Each binary can then
+// declare just its own settings instead of the whole project document.
+func LoadService(name string, target any, logger *logger.Logger, opts ...Option) error {
+	settings := newOptions(opts)
+
+	effective, contentErr := loadContent(settings, nil, logger)
+	if contentErr != nil {
+		return contentErr
+	}
+
+	auditErr := auditPermissions(effective.files, target, []string{name, commonTable}, settings, logger)
+	if auditErr != nil {
+		return auditErr
+	}
+
+	content, sectionErr := serviceSection(effective.content, name, settings)
+	if sectionErr != nil {
+		return sectionErr
+	}
+
+	unmarshalErr := unmarshalTOML(content, target)
+	if unmarshalErr != nil {
+		return fmt.Errorf("failed to unmarshal TOML: %w", unmarshalErr)
+	}
+
+	return revealSecrets(target, settings)
+}
+
+// serviceSection returns the [name] table of content merged over its [common] table,
+// encoded as a TOML document.
+func serviceSection(content []byte, name string, settings *options) ([]byte, error) {
+	table, parseErr := parseTable(content, settings)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	service, found := table[name].(map[string]any)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownService, name)
+	}
+
+	common, _ := table[commonTable].(map[string]any)
+	section := Merge(common, service, settings.merge)
+
+	encoded, marshalErr := toml.Marshal(section)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to encode %s configuration: %w", name, marshalErr)
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,84 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serviceDocument is a project document with a shared table and two services.
+const serviceDocument = `[common]
+log_level = "info"
+region = "eu"
+
+[common.nats]
+url = "nats://localhost:4222"
+
+[tts]
+port = 8080
+log_level = "debug"
+
+[tts.nats]
+subject = "tts.jobs"
+
+[ocr]
+port = 8081
+`
+
+// serviceConfig is the section one service declares.
+type serviceConfig struct {
+	Port     int    `toml:"port"`
+	LogLevel string `toml:"log_level"`
+	Region   string `toml:"region"`
+	NATS     struct {
+		URL     string `toml:"url"`
+		Subject string `toml:"subject"`
+	} `toml:"nats"`
+}
+
+func TestLoadServiceMergesOverCommon(t *testing.T) {
+	localDocument(t, serviceDocument)
+
+	var tts serviceConfig
+
+	require.NoError(t, LoadService("tts", &tts, newTestLogger(t)))
+	assert.Equal(t, 8080, tts.Port)
+	assert.Equal(t, "debug", tts.LogLevel)
+	assert.Equal(t, "eu", tts.Region)
+	assert.Equal(t, "nats://localhost:4222", tts.NATS.URL)
+	assert.Equal(t, "tts.jobs", tts.NATS.Subject)
+
+	var ocr serviceConfig
+
+	require.NoError(t, LoadService("ocr", &ocr, newTestLogger(t)))
+	assert.Equal(t, 8081, ocr.Port)
+	assert.Equal(t, "info", ocr.LogLevel)
+	assert.Empty(t, ocr.NATS.Subject)
+}
+
+func TestLoadServiceWithoutCommon(t *testing.T) {
+	localDocument(t, "[tts]\nport = 8080\n")
+
+	var tts serviceConfig
+
+	require.NoError(t, LoadService("tts", &tts, newTestLogger(t)))
+	assert.Equal(t, 8080, tts.Port)
+}
+
+func TestLoadServiceRejectsUnknownServices(t *testing.T) {
+	localDocument(t, serviceDocument)
+
+	for _, name := range []string{"asr", "common.nats", ""} {
+		var config serviceConfig
+
+		loadErr := LoadService(name, &config, newTestLogger(t))
+		require.ErrorIs(t, loadErr, ErrUnknownService, name)
+	}
+
+	localDocument(t, "tts = \"not a table\"\n")
+
+	var config serviceConfig
+
+	require.ErrorIs(t, LoadService("tts", &config, newTestLogger(t)), ErrUnknownService)
+}
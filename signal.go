@@ -0,0 +1,52 @@
+package configurator
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// WithReloadSignals sets the signals that make Watch re-fetch and re-validate the
+// configuration immediately, e.g. WithReloadSignals(syscall.SIGHUP). Watch listens for no
+// signals by default, so it never takes over a signal the service handles itself.
+func WithReloadSignals(signals ...os.Signal) Option {
+	return func(o *options) {
+		o.reloadSignals = signals
+	}
+}
+
+// reloadRequests returns a channel that receives a value whenever one of the configured
+// reload signals arrives or the configured ReloadTrigger fires, until ctx is done.
+func reloadRequests(ctx context.Context, settings *options) <-chan struct{} {
+	reloads := make(chan struct{}, 1)
+
+	if len(settings.reloadSignals) > 0 {
+		go forwardSignals(ctx, settings.reloadSignals, reloads)
+	}
+
+	if settings.reloadTrigger != nil {
+		go forwardTrigger(ctx, settings.reloadTrigger, reloads)
+	}
+
+	return reloads
+}
+
+// forwardSignals relays the given signals into reloads until ctx is done.
+func forwardSignals(ctx context.Context, signals []os.Signal, reloads chan<- struct{}) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	defer signal.Stop(received)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-received:
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
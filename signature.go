@@ -0,0 +1,147 @@
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/book-expert/logger"
+)
+
+const (
+	// signatureSuffix is appended to the path of the source to locate its detached signature.
+	signatureSuffix = ".asc"
+	// armorPrefix starts an ASCII-armored OpenPGP signature.
+	armorPrefix = "-----BEGIN"
+)
+
+// ErrInvalidSignature is returned when the configuration does not match its detached
+// signature, or the signature was not made by a key in the keyring.
+var ErrInvalidSignature = errors.New("configuration signature verification failed")
+
+// WithSignatureKeyring requires every configuration document, the base document as well
+// as its includes and overlays, to carry a detached OpenPGP signature, read from its
+// source with .asc appended to the path, made by a key in the keyring file at path. The keyring may
+// be armored or binary.
+func WithSignatureKeyring(path string) Option {
+	return func(o *options) {
+		o.signatureKeyring = path
+	}
+}
+
+// verifySignatures checks content, read from source, against the detached OpenPGP and
+// cosign signatures settings require.
+func verifySignatures(settings *options, source string, content []byte, cache *fetchCache, logger *logger.Logger) error {
+	_, signaturesErr := readSignatures(settings, source, content, cache, logger)
+
+	return signaturesErr
+}
+
+// readSignatures reads the detached signatures settings require of the document at
+// source, checks them against content, and returns them by suffix. A missing signature
+// fails with ErrInvalidSignature.
+func readSignatures(settings *options, source string, content []byte, cache *fetchCache, logger *logger.Logger) (map[string][]byte, error) {
+	unpinned, _ := splitPin(source)
+	signatures := make(map[string][]byte)
+
+	for _, required := range requiredSignatures(settings) {
+		signature, _, signatureErr := readSource(signatureSource(unpinned, required.suffix), settings, cache, logger)
+		if signatureErr != nil {
+			return nil, fmt.Errorf("%w: failed to read signature for %s: %w", ErrInvalidSignature, unpinned, signatureErr)
+		}
+
+		checkErr := required.check(settings, unpinned, content, signature)
+		if checkErr != nil {
+			return nil, checkErr
+		}
+
+		signatures[required.suffix] = signature
+	}
+
+	return signatures, nil
+}
+
+// signatureSource returns where the signature with suffix of the document at source is
+// read from: source with suffix appended to its path, ahead of the query string and
+// fragment of a URL.
+func signatureSource(source, suffix string) string {
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil || parsed.Scheme == "" || filepath.IsAbs(source) {
+		return source + suffix
+	}
+
+	parsed.Path += suffix
+	if parsed.RawPath != "" {
+		parsed.RawPath += suffix
+	}
+
+	return parsed.String()
+}
+
+// detachedSignature is a signature stored next to a document, at its source with suffix
+// appended, and the function that checks it.
+type detachedSignature struct {
+	suffix string
+	check  func(settings *options, source string, content, signature []byte) error
+}
+
+// requiredSignatures returns the detached signatures settings require of every document.
+func requiredSignatures(settings *options) []detachedSignature {
+	var required []detachedSignature
+
+	if settings.signatureKeyring != "" {
+		required = append(required, detachedSignature{suffix: signatureSuffix, check: checkSignature})
+	}
+
+	if settings.cosignPublicKey != "" {
+		required = append(required, detachedSignature{suffix: cosignSignatureSuffix, check: checkCosignSignature})
+	}
+
+	return required
+}
+
+// checkSignature checks content, read from source, against its detached OpenPGP
+// signature.
+func checkSignature(settings *options, source string, content, signature []byte) error {
+	keyring, keyringErr := readKeyring(settings.signatureKeyring)
+	if keyringErr != nil {
+		return keyringErr
+	}
+
+	check := openpgp.CheckDetachedSignature
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(armorPrefix)) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+
+	_, checkErr := check(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil)
+
+	if checkErr != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSignature, source, checkErr)
+	}
+
+	return nil
+}
+
+// readKeyring reads the armored or binary OpenPGP keyring at path.
+func readKeyring(path string) (openpgp.EntityList, error) {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read keyring %s: %w", path, readErr)
+	}
+
+	keyring, armoredErr := openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	if armoredErr == nil {
+		return keyring, nil
+	}
+
+	keyring, binaryErr := openpgp.ReadKeyRing(bytes.NewReader(content))
+	if binaryErr != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", path, binaryErr)
+	}
+
+	return keyring, nil
+}
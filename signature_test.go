@@ -0,0 +1,176 @@
+package configurator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signingKey returns a new OpenPGP key and the path of a keyring file holding its
+// public part.
+func signingKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, entityErr := openpgp.NewEntity("Configurator Test", "", "test@example.com", nil)
+	require.NoError(t, entityErr)
+
+	var keyring bytes.Buffer
+	require.NoError(t, entity.Serialize(&keyring))
+
+	path := filepath.Join(t.TempDir(), "keyring.gpg")
+	require.NoError(t, os.WriteFile(path, keyring.Bytes(), 0o600))
+
+	return entity, path
+}
+
+// signDocument returns the armored detached signature of document by entity.
+func signDocument(t *testing.T, entity *openpgp.Entity, document string) string {
+	t.Helper()
+
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&signature, entity, strings.NewReader(document), nil))
+
+	return signature.String()
+}
+
+// signedDocuments returns documents with a .asc signature by entity for every one of
+// them.
+func signedDocuments(t *testing.T, entity *openpgp.Entity, documents map[string]string) map[string]string {
+	t.Helper()
+
+	signed := make(map[string]string, 2*len(documents))
+
+	for path, document := range documents {
+		signed[path] = document
+		signed[path+signatureSuffix] = signDocument(t, entity, document)
+	}
+
+	return signed
+}
+
+// layeredDocuments is a base document with an include and a prod overlay.
+var layeredDocuments = map[string]string{
+	"/project.toml":      "include = [\"nats.toml\"]\n[service]\nname = \"tts\"\n",
+	"/nats.toml":         "[nats]\nurl = \"nats://localhost:4222\"\n",
+	"/project.prod.toml": "[service]\nport = 443\n",
+}
+
+func TestLoadVerifiesEverySignedDocument(t *testing.T) {
+	entity, keyring := signingKey(t)
+	server := serveDocuments(t, signedDocuments(t, entity, layeredDocuments))
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithSignatureKeyring(keyring), WithEnvironment("prod")))
+	assert.Equal(t, map[string]any{"name": "tts", "port": int64(443)}, config["service"])
+	assert.Equal(t, map[string]any{"url": "nats://localhost:4222"}, config["nats"])
+}
+
+func TestLoadRejectsTamperedDocuments(t *testing.T) {
+	for _, path := range []string{"/project.toml", "/nats.toml", "/project.prod.toml"} {
+		t.Run(path, func(t *testing.T) {
+			entity, keyring := signingKey(t)
+			documents := signedDocuments(t, entity, layeredDocuments)
+			documents[path] += "injected = true\n"
+
+			server := serveDocuments(t, documents)
+			t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+			var config map[string]any
+
+			loadErr := Load(&config, newTestLogger(t), WithSignatureKeyring(keyring), WithEnvironment("prod"))
+			require.ErrorIs(t, loadErr, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestLoadRejectsUnsignedOverlay(t *testing.T) {
+	entity, keyring := signingKey(t)
+	documents := signedDocuments(t, entity, layeredDocuments)
+	delete(documents, "/project.prod.toml"+signatureSuffix)
+
+	server := serveDocuments(t, documents)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config map[string]any
+
+	loadErr := Load(&config, newTestLogger(t), WithSignatureKeyring(keyring), WithEnvironment("prod"))
+	require.ErrorIs(t, loadErr, ErrInvalidSignature)
+}
+
+func TestLoadRejectsSignatureByUnknownKey(t *testing.T) {
+	_, keyring := signingKey(t)
+	stranger, _ := signingKey(t)
+
+	server := serveDocuments(t, signedDocuments(t, stranger, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	}))
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	var config map[string]any
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t), WithSignatureKeyring(keyring)), ErrInvalidSignature)
+}
+
+func TestVendorCopiesSignatures(t *testing.T) {
+	entity, keyring := signingKey(t)
+	documents := signedDocuments(t, entity, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	server := serveDocuments(t, documents)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	path := filepath.Join(t.TempDir(), "project.toml")
+	log := newTestLogger(t)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	require.NoError(t, Vendor(path, log, WithSignatureKeyring(keyring), WithCacheKey(key)))
+
+	signature, readErr := os.ReadFile(path + signatureSuffix)
+	require.NoError(t, readErr)
+	assert.Equal(t, documents["/project.toml"+signatureSuffix], string(signature))
+
+	server.Close()
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, log, WithVendoredCopy(path), WithSignatureKeyring(keyring), WithCacheKey(key)))
+	assert.Equal(t, "tts", config.Service.Name)
+}
+
+func TestSignatureSource(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"/etc/project.toml":                          "/etc/project.toml.asc",
+		"project.toml":                               "project.toml.asc",
+		"file:///etc/project.toml":                   "file:///etc/project.toml.asc",
+		"https://cfg.example/project.toml":           "https://cfg.example/project.toml.asc",
+		"https://cfg.example/project.toml?ref=main":  "https://cfg.example/project.toml.asc?ref=main",
+		"https://cfg.example/project.toml#prod":      "https://cfg.example/project.toml.asc#prod",
+		"https://cfg.example/my%2Fproject.toml?a=b":  "https://cfg.example/my%2Fproject.toml.asc?a=b",
+		"unix:///run/config.sock:/project.toml?ref=": "unix:///run/config.sock:/project.toml.asc?ref=",
+	}
+
+	for source, want := range tests {
+		assert.Equal(t, want, signatureSource(source, signatureSuffix), source)
+	}
+}
+
+func TestLoadVerifiesSignatureOfURLWithQuery(t *testing.T) {
+	entity, keyring := signingKey(t)
+	server := serveDocuments(t, signedDocuments(t, entity, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	}))
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml?ref=main")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithSignatureKeyring(keyring)))
+	assert.Equal(t, map[string]any{"name": "tts"}, config["service"])
+}
@@ -0,0 +1,289 @@
+package configurator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SigV4Variable names the environment variable that, when true, signs configuration
+	// requests with AWS Signature Version 4, used when WithSigV4 is not given.
+	SigV4Variable = "PROJECT_TOML_SIGV4"
+	// defaultAWSRegion is used when neither WithSigV4 nor the environment names a region.
+	defaultAWSRegion = "us-east-1"
+	// defaultAWSProfile is the shared credentials profile used when AWS_PROFILE is unset.
+	defaultAWSProfile = "default"
+	// s3Service is the service name requests are signed for.
+	s3Service = "s3"
+	// sigV4Algorithm identifies the signing algorithm in the Authorization header.
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	// amzDateFormat and amzDayFormat format the request time and credential scope date.
+	amzDateFormat = "20060102T150405Z"
+	amzDayFormat  = "20060102"
+	// emptyPayloadHash is the SHA-256 of the empty body of a GET request.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// ErrAWSCredentialsNotFound is returned when SigV4 signing is enabled but no AWS
+// credentials are available.
+var ErrAWSCredentialsNotFound = errors.New("AWS credentials not found")
+
+// awsCredentials is a set of AWS access keys, with the session token and expiry of
+// temporary ones.
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	expiry          time.Time
+}
+
+// WithSigV4 signs configuration requests with AWS Signature Version 4 so that
+// configuration can be read from a private S3 or MinIO bucket. An empty region falls
+// back to AWS_REGION, AWS_DEFAULT_REGION, and then us-east-1. Credentials are found
+// through the standard chain: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN; the AWS_PROFILE profile of the shared credentials file; a web
+// identity token (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN, as on EKS); the ECS or
+// EKS Pod Identity container endpoint; and the EC2 instance metadata service (IMDSv2).
+func WithSigV4(region string) Option {
+	return func(o *options) {
+		o.sigV4 = true
+		o.sigV4Region = region
+	}
+}
+
+// defaultSigV4 reports whether SigV4Variable is set to true.
+func defaultSigV4() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(SigV4Variable))
+
+	return enabled
+}
+
+// signSigV4 adds a SigV4 Authorization header to req when signing is enabled. Only the
+// host and x-amz-* headers are signed, so headers added afterwards do not invalidate it.
+func (o *options) signSigV4(req *http.Request) error {
+	if !o.sigV4 {
+		return nil
+	}
+
+	credentials, credentialsErr := loadAWSCredentials(req.Context(), o.urlTimeout)
+	if credentialsErr != nil {
+		return credentialsErr
+	}
+
+	signRequest(req, credentials, awsRegion(o.sigV4Region), time.Now().UTC())
+
+	return nil
+}
+
+// signRequest signs req for S3 in region with credentials, as of now.
+func signRequest(req *http.Request, credentials awsCredentials, region string, now time.Time) {
+	day := now.Format(amzDayFormat)
+	scope := strings.Join([]string{day, region, s3Service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	if credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(amzDateFormat),
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := []byte("AWS4" + credentials.secretAccessKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, credentials.accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalizeHeaders returns the canonical header block and signed header list for the
+// host and x-amz-* headers of req.
+func canonicalizeHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	values := map[string]string{"host": host}
+
+	for name, headerValues := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			values[lower] = strings.Join(headerValues, ",")
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var canonical strings.Builder
+
+	for _, name := range names {
+		canonical.WriteString(name + ":" + strings.TrimSpace(values[name]) + "\n")
+	}
+
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery encodes query with sorted keys and values and RFC 3986 escaping.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes value as SigV4 requires, encoding spaces as %20.
+func uriEncode(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// hexSHA256 returns the hex-encoded SHA-256 digest of value.
+func hexSHA256(value string) string {
+	digest := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// awsRegion returns region or the region named by the environment.
+func awsRegion(region string) string {
+	for _, candidate := range []string{region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if candidate != "" {
+			return candidate
+		}
+	}
+
+	return defaultAWSRegion
+}
+
+// loadAWSCredentials reads credentials from the environment or the shared credentials
+// file and, failing both, obtains temporary credentials with remoteAWSCredentials. A
+// profile named by AWS_PROFILE must be in the shared credentials file.
+func loadAWSCredentials(ctx context.Context, timeout time.Duration) (awsCredentials, error) {
+	fromEnvironment := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if fromEnvironment.accessKeyID != "" && fromEnvironment.secretAccessKey != "" {
+		return fromEnvironment, nil
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	explicitProfile := profile != ""
+
+	if !explicitProfile {
+		profile = defaultAWSProfile
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, homeErr := os.UserHomeDir()
+		if homeErr == nil {
+			path = filepath.Join(home, ".aws", "credentials")
+		}
+	}
+
+	if path != "" {
+		fromFile, fileErr := readSharedCredentials(path, profile)
+		if fileErr == nil || explicitProfile {
+			return fromFile, fileErr
+		}
+	}
+
+	return remoteAWSCredentials(ctx, timeout)
+}
+
+// readSharedCredentials reads the credentials of profile from the INI-style shared
+// credentials file at path.
+func readSharedCredentials(path, profile string) (awsCredentials, error) {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return awsCredentials{}, fmt.Errorf("%w: %w", ErrAWSCredentialsNotFound, readErr)
+	}
+
+	var (
+		credentials awsCredentials
+		inProfile   bool
+	)
+
+	for line := range strings.Lines(string(content)) {
+		line = strings.TrimSpace(line)
+
+		if section, isSection := strings.CutPrefix(line, "["); isSection {
+			inProfile = strings.TrimSpace(strings.TrimSuffix(section, "]")) == profile
+
+			continue
+		}
+
+		name, value, isPair := strings.Cut(line, "=")
+		if !inProfile || !isPair {
+			continue
+		}
+
+		switch strings.TrimSpace(name) {
+		case "aws_access_key_id":
+			credentials.accessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			credentials.secretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			credentials.sessionToken = strings.TrimSpace(value)
+		}
+	}
+
+	if credentials.accessKeyID == "" || credentials.secretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("%w: no keys for profile %s in %s", ErrAWSCredentialsNotFound, profile, path)
+	}
+
+	return credentials, nil
+}
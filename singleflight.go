@@ -0,0 +1,147 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+// flight is a fetch in progress whose result is shared with every caller that asked
+// for the same request while it ran. ctx is the context of the caller making the fetch.
+type flight struct {
+	done      chan struct{}
+	ctx       context.Context
+	body      []byte
+	format    string
+	transient bool
+	err       error
+}
+
+// flights holds the fetches in progress by request identity.
+var flights = struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}{calls: make(map[string]*flight)}
+
+// fetchIdentity lists everything besides the cache that determines the outcome of a
+// fetch, so only requests that would be identical are coalesced.
+type fetchIdentity struct {
+	url         string
+	bearerToken string
+	username    string
+	password    string
+	headers     http.Header
+	credHosts   []string
+	userAgent   string
+	oauth2      OAuth2Config
+	sigV4       bool
+	sigV4Region string
+	hosts       []string
+	https       bool
+	block       bool
+	redirects   RedirectPolicy
+	clientCert  string
+	clientKey   string
+	caBundle    string
+	client      *http.Client
+	retry       RetryPolicy
+	maxBodySize int64
+	timeout     time.Duration
+	cacheDir    string
+	atRestKey   string
+	plaintext   bool
+	offline     bool
+	stale       bool
+	maxStale    time.Duration
+}
+
+// fetchKey returns the key under which fetches of url with settings are coalesced.
+func fetchKey(url string, settings *options) string {
+	identity := fetchIdentity{
+		url:         url,
+		bearerToken: settings.bearerToken,
+		username:    settings.username,
+		password:    settings.password,
+		headers:     settings.headers,
+		credHosts:   settings.credentialHosts,
+		userAgent:   settings.userAgent,
+		sigV4:       settings.sigV4,
+		sigV4Region: settings.sigV4Region,
+		hosts:       settings.allowedHosts,
+		https:       settings.requireHTTPS,
+		block:       settings.blockInternal,
+		redirects:   settings.redirects,
+		clientCert:  settings.clientCert,
+		clientKey:   settings.clientKey,
+		caBundle:    settings.caBundle,
+		client:      settings.httpClient,
+		retry:       settings.retry,
+		maxBodySize: settings.maxBodySize,
+		timeout:     settings.urlTimeout,
+		cacheDir:    settings.cacheDir,
+		atRestKey:   settings.atRestKeyDigest(),
+		plaintext:   settings.plaintextCache,
+		offline:     settings.offline,
+		stale:       settings.staleFallback,
+		maxStale:    settings.maxStale,
+	}
+
+	if settings.oauth2 != nil {
+		identity.oauth2 = *settings.oauth2
+	}
+
+	digest := sha256.Sum256(fmt.Appendf(nil, "%#v", identity))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// fetchShared fetches url with retries, or waits for an identical fetch already in
+// progress, possibly from another Load, and shares its result. A waiting caller stops
+// waiting when its own context is cancelled, and fetches itself when the shared fetch
+// failed because its caller's context was cancelled. Fetches reporting their progress
+// are never shared, since each caller's callback must see its own download.
+func fetchShared(url string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, bool, error) {
+	if settings.progress != nil {
+		return fetchWithRetries(url, settings, cache, logger)
+	}
+
+	key := fetchKey(url, settings)
+
+	flights.mu.Lock()
+
+	if call, inProgress := flights.calls[key]; inProgress {
+		flights.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-settings.loadContext.Done():
+			return nil, "", false, fmt.Errorf("failed to fetch %s: %w", url, settings.loadContext.Err())
+		}
+
+		if call.err != nil && call.ctx.Err() != nil && settings.loadContext.Err() == nil {
+			return fetchShared(url, settings, cache, logger)
+		}
+
+		return bytes.Clone(call.body), call.format, call.transient, call.err
+	}
+
+	call := &flight{done: make(chan struct{}), ctx: settings.loadContext}
+	flights.calls[key] = call
+	flights.mu.Unlock()
+
+	call.body, call.format, call.transient, call.err = fetchWithRetries(url, settings, cache, logger)
+
+	flights.mu.Lock()
+	delete(flights.calls, key)
+	flights.mu.Unlock()
+	close(call.done)
+
+	return call.body, call.format, call.transient, call.err
+}
@@ -0,0 +1,209 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// joinDelay is how long the tests give concurrent fetches to join one in progress.
+const joinDelay = 100 * time.Millisecond
+
+// gatedServer answers every request with body once release is closed, counting the
+// requests and signalling the first on started.
+type gatedServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	started  chan struct{}
+	release  chan struct{}
+}
+
+// newGatedServer starts a gatedServer answering with body.
+func newGatedServer(t *testing.T, body string) *gatedServer {
+	t.Helper()
+
+	server := &gatedServer{started: make(chan struct{}), release: make(chan struct{})}
+
+	var startOnce sync.Once
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.requests.Add(1)
+		startOnce.Do(func() { close(server.started) })
+
+		select {
+		case <-server.release:
+		case <-r.Context().Done():
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// fetchResult is the outcome of one fetchShared call.
+type fetchResult struct {
+	body []byte
+	err  error
+}
+
+// startFetch runs fetchShared for url with opts in the background.
+func startFetch(t *testing.T, url string, opts ...Option) <-chan fetchResult {
+	t.Helper()
+
+	log := newTestLogger(t)
+	result := make(chan fetchResult, 1)
+
+	go func() {
+		body, _, _, fetchErr := fetchShared(url, newOptions(opts), nil, log)
+		result <- fetchResult{body: body, err: fetchErr}
+	}()
+
+	return result
+}
+
+func TestFetchSharedCoalescesIdenticalFetches(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"shared\"\n")
+	url := server.URL + "/project.toml"
+
+	results := []<-chan fetchResult{startFetch(t, url)}
+	<-server.started
+
+	for range 4 {
+		results = append(results, startFetch(t, url))
+	}
+
+	time.Sleep(joinDelay)
+	close(server.release)
+
+	for _, result := range results {
+		outcome := <-result
+		require.NoError(t, outcome.err)
+		assert.Equal(t, "name = \"shared\"\n", string(outcome.body))
+	}
+
+	assert.Equal(t, int32(1), server.requests.Load())
+}
+
+func TestFetchSharedKeepsDifferentCredentialsApart(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"private\"\n")
+	url := server.URL + "/project.toml"
+
+	first := startFetch(t, url, WithBearerToken("first"))
+	<-server.started
+
+	second := startFetch(t, url, WithBearerToken("second"))
+
+	time.Sleep(joinDelay)
+	close(server.release)
+
+	require.NoError(t, (<-first).err)
+	require.NoError(t, (<-second).err)
+	assert.Equal(t, int32(2), server.requests.Load())
+}
+
+func TestFetchSharedKeepsDifferentCacheKeysApart(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"cached\"\n")
+	url := server.URL + "/project.toml"
+	dir := t.TempDir()
+
+	first := startFetch(t, url, WithCacheDir(dir), WithCacheKey(bytes.Repeat([]byte{1}, 32)))
+	<-server.started
+
+	second := startFetch(t, url, WithCacheDir(dir), WithCacheKey(bytes.Repeat([]byte{2}, 32)))
+
+	time.Sleep(joinDelay)
+	close(server.release)
+
+	require.NoError(t, (<-first).err)
+	require.NoError(t, (<-second).err)
+	assert.Equal(t, int32(2), server.requests.Load())
+
+	assert.Equal(t, fetchKey(url, newOptions([]Option{WithCacheKey([]byte("key"))})),
+		fetchKey(url, newOptions([]Option{WithCacheKey([]byte("key"))})))
+	assert.NotEqual(t, fetchKey(url, newOptions([]Option{WithSecretKey([]byte("a"))})),
+		fetchKey(url, newOptions([]Option{WithSecretKey([]byte("b"))})))
+}
+
+func TestFetchSharedDoesNotShareReportedFetches(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"reported\"\n")
+	url := server.URL + "/project.toml"
+	report := WithProgress(func(Progress) error { return nil })
+
+	first := startFetch(t, url, report)
+	<-server.started
+
+	second := startFetch(t, url, report)
+
+	time.Sleep(joinDelay)
+	close(server.release)
+
+	require.NoError(t, (<-first).err)
+	require.NoError(t, (<-second).err)
+	assert.Equal(t, int32(2), server.requests.Load())
+}
+
+func TestFetchSharedFollowerStopsWaitingOnItsOwnCancellation(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"slow\"\n")
+	url := server.URL + "/project.toml"
+
+	leader := startFetch(t, url)
+	<-server.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	follower := startFetch(t, url, WithContext(ctx))
+
+	time.Sleep(joinDelay)
+	cancel()
+
+	require.ErrorIs(t, (<-follower).err, context.Canceled)
+
+	close(server.release)
+	require.NoError(t, (<-leader).err)
+	assert.Equal(t, int32(1), server.requests.Load())
+}
+
+func TestFetchSharedFollowerRefetchesWhenTheLeaderIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	server := newGatedServer(t, "name = \"retried\"\n")
+	url := server.URL + "/project.toml"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := startFetch(t, url, WithContext(ctx))
+	<-server.started
+
+	follower := startFetch(t, url)
+
+	time.Sleep(joinDelay)
+	cancel()
+
+	require.ErrorIs(t, (<-leader).err, context.Canceled)
+
+	close(server.release)
+
+	outcome := <-follower
+	require.NoError(t, outcome.err)
+	assert.Equal(t, "name = \"retried\"\n", string(outcome.body))
+	assert.Equal(t, int32(2), server.requests.Load())
+}
@@ -0,0 +1,81 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// unixScheme selects a Unix domain socket, as in unix:///var/run/config.sock:/project.toml.
+	unixScheme = "unix"
+	// socketHost is the placeholder host of requests sent over a Unix domain socket.
+	socketHost = "localhost"
+)
+
+// ErrInvalidSocketURL is returned when a unix:// URL does not name a socket.
+var ErrInvalidSocketURL = errors.New("invalid unix socket URL")
+
+// socketKey identifies the cached transport of a Unix domain socket.
+type socketKey string
+
+// splitSocketURL splits a unix://<socket>:<path> URL into the HTTP URL requested over
+// the socket and the socket path. The boolean result is false for other URLs.
+func splitSocketURL(source string) (string, string, bool, error) {
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil || parsed.Scheme != unixScheme {
+		return source, "", false, nil
+	}
+
+	socket, path, hasPath := strings.Cut(parsed.Path, ":")
+	if socket == "" {
+		return "", "", true, fmt.Errorf("%w: %s", ErrInvalidSocketURL, source)
+	}
+
+	if !hasPath || path == "" {
+		path = "/"
+	}
+
+	requestURL := url.URL{Scheme: "http", Host: socketHost, Path: path, RawQuery: parsed.RawQuery}
+
+	return requestURL.String(), socket, true, nil
+}
+
+// checkSource applies the URL checks of settings to a request for target. Requests over a
+// Unix domain socket never leave the machine, so they are only refused when internal
+// addresses are blocked.
+func checkSource(target *url.URL, isSocket bool, settings *options) error {
+	if !isSocket {
+		return checkURL(target, settings)
+	}
+
+	if settings.blockInternal {
+		return fmt.Errorf("%w: unix socket", ErrInternalAddress)
+	}
+
+	return nil
+}
+
+// socketTransport returns the cached transport that sends every request to socket.
+func socketTransport(socket string) http.RoundTripper {
+	key := socketKey(socket)
+
+	if cached, found := transports.load(key); found {
+		return cached
+	}
+
+	baseTransport, _ := http.DefaultTransport.(*http.Transport)
+	transport := baseTransport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+
+		return dialer.DialContext(ctx, unixScheme, socket)
+	}
+
+	return transports.loadOrStore(key, transport)
+}
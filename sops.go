@@ -0,0 +1,129 @@
+package configurator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// sopsCommand is the SOPS binary used to decrypt documents.
+	sopsCommand = "sops"
+	// sopsMetadataKey is the top-level key SOPS stores its metadata under.
+	sopsMetadataKey = "sops"
+	// sopsFormatJSON and sopsFormatYAML are the SOPS input types Load recognizes.
+	sopsFormatJSON = "json"
+	sopsFormatYAML = "yaml"
+	// sopsWaitDelay bounds how long a killed sops process may keep its output open.
+	sopsWaitDelay = time.Second
+)
+
+// ErrSOPSDecrypt is returned when a SOPS-encrypted document cannot be decrypted.
+var ErrSOPSDecrypt = errors.New("failed to decrypt SOPS document")
+
+// sopsFormat reports whether content is a SOPS-encrypted document and, if so, whether
+// it is JSON or YAML. SOPS has no TOML format, so TOML settings are encrypted as JSON or
+// YAML documents of the same shape.
+func sopsFormat(content []byte) (string, bool) {
+	trimmed := bytes.TrimSpace(content)
+
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var document map[string]json.RawMessage
+
+		if json.Unmarshal(trimmed, &document) != nil {
+			return "", false
+		}
+
+		_, encrypted := document[sopsMetadataKey]
+
+		return sopsFormatJSON, encrypted
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if strings.TrimRight(scanner.Text(), " \t") == sopsMetadataKey+":" {
+			return sopsFormatYAML, true
+		}
+	}
+
+	return "", false
+}
+
+// decryptSOPS decrypts a SOPS document with the sops binary, which finds the age, KMS,
+// or PGP keys the usual way, and returns its settings as a table. The binary is killed
+// when the URL timeout of settings passes or the load is cancelled, since it may call a
+// KMS over the network.
+func decryptSOPS(content []byte, format string, settings *options) (map[string]any, error) {
+	var stdout, stderr bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(settings.loadContext, settings.urlTimeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, sopsCommand, "--decrypt", "--input-type", format, "--output-type", sopsFormatJSON, "/dev/stdin")
+	command.Stdin = bytes.NewReader(content)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	command.WaitDelay = sopsWaitDelay
+
+	runErr := command.Run()
+	if ctx.Err() != nil {
+		runErr = fmt.Errorf("%w: %w", runErr, ctx.Err())
+	}
+
+	if runErr != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %w: %s", ErrSOPSDecrypt, runErr, message)
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrSOPSDecrypt, runErr)
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	decoder.UseNumber()
+
+	var table map[string]any
+
+	decodeErr := decoder.Decode(&table)
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSOPSDecrypt, decodeErr)
+	}
+
+	normalized, _ := normalizeJSON(table).(map[string]any)
+
+	return normalized, nil
+}
+
+// normalizeJSON converts the json.Number values of a decoded JSON document to the
+// int64 or float64 values a TOML document would hold.
+func normalizeJSON(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, nested := range typed {
+			typed[key] = normalizeJSON(nested)
+		}
+
+		return typed
+	case []any:
+		for index, element := range typed {
+			typed[index] = normalizeJSON(element)
+		}
+
+		return typed
+	case json.Number:
+		if integer, intErr := typed.Int64(); intErr == nil {
+			return integer
+		}
+
+		float, _ := typed.Float64()
+
+		return float
+	default:
+		return value
+	}
+}
@@ -0,0 +1,84 @@
+package configurator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSOPS puts a sops executable running script first on the PATH, standing in for the
+// real binary.
+func fakeSOPS(t *testing.T, script string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sopsCommand), []byte("#!/bin/sh\n"+script), 0o700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// sopsDocuments are SOPS-encrypted documents of the same settings in both formats.
+var sopsDocuments = map[string]string{
+	sopsFormatJSON: `{"service":{"name":"ENC[AES256_GCM,data:abc,type:str]"},"sops":{"version":"3.9.0"}}`,
+	sopsFormatYAML: "service:\n    name: ENC[AES256_GCM,data:abc,type:str]\nsops:\n    version: 3.9.0\n",
+}
+
+func TestLoadDecryptsSOPSDocuments(t *testing.T) {
+	for format, content := range sopsDocuments {
+		t.Run(format, func(t *testing.T) {
+			fakeSOPS(t, `[ "$*" = "--decrypt --input-type `+format+` --output-type json /dev/stdin" ] || exit 3
+grep -q ENC >/dev/null || exit 4
+printf '{"service":{"name":"tts","port":8080}}'
+`)
+			localDocument(t, content)
+
+			var config testConfig
+
+			require.NoError(t, Load(&config, newTestLogger(t)))
+			assert.Equal(t, "tts", config.Service.Name)
+			assert.Equal(t, 8080, config.Service.Port)
+		})
+	}
+}
+
+func TestLoadReportsSOPSFailures(t *testing.T) {
+	fakeSOPS(t, "echo 'no key could decrypt the data key' >&2\nexit 128\n")
+	localDocument(t, sopsDocuments[sopsFormatJSON])
+
+	var config testConfig
+
+	loadErr := Load(&config, newTestLogger(t))
+	require.ErrorIs(t, loadErr, ErrSOPSDecrypt)
+	assert.ErrorContains(t, loadErr, "no key could decrypt the data key")
+}
+
+func TestLoadBoundsSOPSByURLTimeout(t *testing.T) {
+	fakeSOPS(t, "exec sleep 10\n")
+	localDocument(t, sopsDocuments[sopsFormatYAML])
+
+	var config testConfig
+
+	started := time.Now()
+	loadErr := Load(&config, newTestLogger(t), WithURLTimeout(100*time.Millisecond))
+	require.ErrorIs(t, loadErr, ErrSOPSDecrypt)
+	require.ErrorIs(t, loadErr, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestSOPSFormatIgnoresPlainDocuments(t *testing.T) {
+	t.Parallel()
+
+	for _, content := range []string{
+		`{"service":{"name":"tts"}}`,
+		"service:\n  name: tts\n",
+		"[service]\nname = \"sops:\"\n",
+		`{"not json`,
+	} {
+		_, encrypted := sopsFormat([]byte(content))
+		assert.False(t, encrypted, content)
+	}
+}
@@ -0,0 +1,95 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/book-expert/logger"
+)
+
+const (
+	// fileScheme is the URL scheme that marks PROJECT_TOML as a local file.
+	fileScheme = "file"
+	// pinPrefix starts the fragment that pins a source to the SHA-256 digest of its content.
+	pinPrefix = "#sha256="
+)
+
+var (
+	// ErrSourceNotFound is returned when a configuration file does not exist or a URL answers 404.
+	ErrSourceNotFound = errors.New("configuration source not found")
+	// ErrDigestMismatch is returned when a source's content does not match its pinned digest.
+	ErrDigestMismatch = errors.New("configuration digest mismatch")
+)
+
+// localPath reports whether source refers to a local file, either as a file:// URL or a
+// plain path without a URL scheme, and returns that path.
+func localPath(source string) (string, bool) {
+	parsed, parseErr := url.Parse(source)
+	if parseErr != nil || filepath.IsAbs(source) {
+		return source, true
+	}
+
+	switch parsed.Scheme {
+	case "":
+		return source, true
+	case fileScheme:
+		return parsed.Path, true
+	default:
+		return "", false
+	}
+}
+
+// readSource reads the document at source, reading local files from disk and fetching
+// everything else over HTTP. It returns the document as served together with its format,
+// which is empty for TOML and names JSON or YAML when a server's Content-Type said so;
+// convertDocument turns it into TOML once the document has been verified. A source
+// ending in #sha256=<digest> must match that digest.
+func readSource(source string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, error) {
+	unpinned, digest := splitPin(source)
+
+	content, format, readErr := readUnpinned(unpinned, settings, cache, logger)
+	if readErr != nil {
+		return nil, "", readErr
+	}
+
+	if digest != "" && contentHash(content) != digest {
+		return nil, "", fmt.Errorf("%w: %s", ErrDigestMismatch, unpinned)
+	}
+
+	return content, format, nil
+}
+
+// readUnpinned reads the document at source without checking a pinned digest.
+func readUnpinned(source string, settings *options, cache *fetchCache, logger *logger.Logger) ([]byte, string, error) {
+	path, isLocal := localPath(source)
+	if !isLocal {
+		return fetchURL(source, settings, cache, logger)
+	}
+
+	content, readErr := os.ReadFile(path)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("%w: %w", ErrSourceNotFound, readErr)
+	}
+
+	if readErr != nil {
+		return nil, "", fmt.Errorf("failed to read configuration file: %w", readErr)
+	}
+
+	return content, "", nil
+}
+
+// splitPin separates a #sha256=<digest> pin from source, returning the source without
+// it and the lower-cased hex digest, which is empty when source is not pinned.
+func splitPin(source string) (string, string) {
+	index := strings.LastIndex(source, pinPrefix)
+	if index < 0 {
+		return source, ""
+	}
+
+	return source[:index], strings.ToLower(source[index+len(pinPrefix):])
+}
@@ -0,0 +1,91 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pinnedDocument is the document the pin tests serve.
+const pinnedDocument = "[service]\nname = \"tts\"\nport = 8080\n"
+
+func TestLoadVerifiesPinnedDigests(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "include = [\"nats.toml#sha256=" + contentHash([]byte("[nats]\nurl = \"nats://a\"\n")) + "\"]\n" + pinnedDocument,
+		"/nats.toml":    "[nats]\nurl = \"nats://a\"\n",
+	})
+
+	for name, digest := range map[string]string{
+		"lower case": contentHash([]byte(pinnedDocument)),
+		"upper case": strings.ToUpper(contentHash([]byte(pinnedDocument))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "project.toml")
+			require.NoError(t, os.WriteFile(path, []byte(pinnedDocument), 0o600))
+			t.Setenv("PROJECT_TOML", path+pinPrefix+digest)
+
+			var config testConfig
+
+			require.NoError(t, Load(&config, newTestLogger(t)))
+			assert.Equal(t, 8080, config.Service.Port)
+		})
+	}
+
+	t.Run("pinned include", func(t *testing.T) {
+		t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+		var config map[string]any
+
+		require.NoError(t, Load(&config, newTestLogger(t)))
+		assert.Equal(t, map[string]any{"url": "nats://a"}, config["nats"])
+	})
+}
+
+func TestLoadRejectsDigestMismatches(t *testing.T) {
+	wrong := contentHash([]byte("something else"))
+	server := serveDocuments(t, map[string]string{
+		"/project.toml":  pinnedDocument,
+		"/includes.toml": "include = [\"nats.toml#sha256=" + wrong + "\"]\n",
+		"/nats.toml":     "[nats]\nurl = \"nats://a\"\n",
+	})
+
+	for name, source := range map[string]string{
+		"base document": server.URL + "/project.toml" + pinPrefix + wrong,
+		"include":       server.URL + "/includes.toml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROJECT_TOML", source)
+
+			var config map[string]any
+
+			require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrDigestMismatch)
+		})
+	}
+
+	t.Run("local file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "project.toml")
+		require.NoError(t, os.WriteFile(path, []byte(pinnedDocument), 0o600))
+		t.Setenv("PROJECT_TOML", path+pinPrefix+wrong)
+
+		var config testConfig
+
+		require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrDigestMismatch)
+	})
+}
+
+func TestSplitPin(t *testing.T) {
+	t.Parallel()
+
+	source, digest := splitPin("https://host/project.toml#sha256=ABCdef")
+	assert.Equal(t, "https://host/project.toml", source)
+	assert.Equal(t, "abcdef", digest)
+
+	source, digest = splitPin("https://host/project.toml")
+	assert.Equal(t, "https://host/project.toml", source)
+	assert.Empty(t, digest)
+}
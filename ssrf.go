@@ -0,0 +1,84 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// BlockInternalVariable names the environment variable that, when true, blocks
+	// fetches from internal addresses, used when WithBlockInternalAddresses is not given.
+	BlockInternalVariable = "PROJECT_TOML_BLOCK_INTERNAL"
+	// dialTimeout and dialKeepAlive match the dialer of http.DefaultTransport.
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// ErrInternalAddress is returned when a fetch would connect to a loopback, link-local,
+// or cloud metadata address while those are blocked.
+var ErrInternalAddress = errors.New("configuration fetch to internal address blocked")
+
+// metadataAddresses lists cloud instance metadata endpoints outside the link-local range.
+var metadataAddresses = []netip.Addr{
+	netip.MustParseAddr("fd00:ec2::254"),
+	netip.MustParseAddr("100.100.100.200"),
+}
+
+// WithBlockInternalAddresses refuses to fetch configuration from loopback, link-local,
+// and cloud metadata addresses such as 169.254.169.254, whatever host name resolves to
+// them. Environment proxies are bypassed so the check sees the real destination.
+func WithBlockInternalAddresses(block bool) Option {
+	return func(o *options) {
+		o.blockInternal = block
+	}
+}
+
+// defaultBlockInternal reports whether BlockInternalVariable is set to true.
+func defaultBlockInternal() bool {
+	block, _ := strconv.ParseBool(os.Getenv(BlockInternalVariable))
+
+	return block
+}
+
+// blockInternalDials makes transport check every address it connects to, after name
+// resolution, so DNS tricks cannot reach internal addresses, and bypass proxies.
+func blockInternalDials(transport *http.Transport) {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: dialKeepAlive,
+		Control:   rejectInternalAddress,
+	}
+
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	// A custom TLS dialer would connect without the check, so TLS runs over DialContext.
+	transport.DialTLSContext = nil
+	transport.DialTLS = nil
+}
+
+// rejectInternalAddress is a net.Dialer Control function that fails connections to
+// internal addresses.
+func rejectInternalAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, parseErr := netip.ParseAddrPort(address)
+	if parseErr != nil {
+		return fmt.Errorf("%w: %s", ErrInternalAddress, address)
+	}
+
+	addr := addrPort.Addr().Unmap()
+
+	switch {
+	case addr.IsLoopback(), addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(),
+		addr.IsUnspecified(), slices.Contains(metadataAddresses, addr):
+		return fmt.Errorf("%w: %s", ErrInternalAddress, addr)
+	default:
+		return nil
+	}
+}
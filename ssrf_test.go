@@ -0,0 +1,99 @@
+package configurator
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectInternalAddress(t *testing.T) {
+	t.Parallel()
+
+	blocked := []string{
+		"127.0.0.1:80",
+		"127.10.0.1:443",
+		"[::1]:80",
+		"[::ffff:127.0.0.1]:80",
+		"169.254.169.254:80",
+		"[fe80::1]:80",
+		"0.0.0.0:80",
+		"[::]:80",
+		"[fd00:ec2::254]:80",
+		"100.100.100.200:80",
+		"not-an-address",
+	}
+
+	for _, address := range blocked {
+		require.ErrorIs(t, rejectInternalAddress("tcp", address, nil), ErrInternalAddress, address)
+	}
+
+	for _, address := range []string{"93.184.216.34:443", "10.0.0.1:80", "[2606:4700::1]:443"} {
+		assert.NoError(t, rejectInternalAddress("tcp", address, nil), address)
+	}
+}
+
+func TestLoadBlocksInternalAddresses(t *testing.T) {
+	server := serveDocuments(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	localhostURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/project.toml"
+
+	tests := map[string]struct {
+		url  string
+		opts []Option
+	}{
+		"address":              {server.URL + "/project.toml", []Option{WithBlockInternalAddresses(true)}},
+		"name resolving there": {localhostURL, []Option{WithBlockInternalAddresses(true)}},
+		"custom client": {server.URL + "/project.toml", []Option{
+			WithBlockInternalAddresses(true),
+			WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}),
+		}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROJECT_TOML", test.url)
+
+			var config testConfig
+
+			require.ErrorIs(t, Load(&config, newTestLogger(t), test.opts...), ErrInternalAddress)
+		})
+	}
+}
+
+func TestLoadBlocksInternalAddressesFromEnvironment(t *testing.T) {
+	server := serveDocuments(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+	t.Setenv(BlockInternalVariable, "true")
+
+	var config testConfig
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrInternalAddress)
+
+	t.Setenv(BlockInternalVariable, "false")
+	require.NoError(t, Load(&config, newTestLogger(t)))
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestLoadRefusesTransportThatCannotBlockInternalAddresses(t *testing.T) {
+	server := serveDocuments(t, map[string]string{"/project.toml": "[service]\nname = \"tts\"\n"})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	client := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+
+	var config testConfig
+
+	loadErr := Load(&config, newTestLogger(t), WithBlockInternalAddresses(true), WithHTTPClient(client))
+	require.ErrorIs(t, loadErr, ErrTransportNotSupported)
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithHTTPClient(client)))
+	assert.Equal(t, "tts", config.Service.Name)
+}
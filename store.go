@@ -0,0 +1,191 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+// DefaultHistorySize is the number of configuration snapshots a Store keeps for rollback.
+const DefaultHistorySize = 10
+
+// ErrNoSnapshot is returned when Rollback is asked for a snapshot the Store does not hold.
+var ErrNoSnapshot = errors.New("no such configuration snapshot")
+
+// Snapshot is a configuration held by a Store together with its load metadata.
+type Snapshot[T any] struct {
+	Config   *T
+	Hash     string
+	Source   string
+	LoadedAt time.Time
+}
+
+// WithHistorySize sets how many snapshots a Store keeps for rollback.
+func WithHistorySize(size int) Option {
+	return func(o *options) {
+		o.historySize = size
+	}
+}
+
+// withAwaitApply makes Watch wait for the consumer to report whether each new
+// configuration was applied, so one that participants refused is counted as a failed
+// reload and offered again.
+func withAwaitApply() Option {
+	return func(o *options) {
+		o.awaitApply = true
+	}
+}
+
+// Store holds the current configuration behind an atomic pointer. Readers get lock-free
+// snapshots while a background Watch swaps in updated configurations. The most recent
+// snapshots are retained so a bad configuration can be rolled back.
+type Store[T any] struct {
+	current atomic.Pointer[T]
+	logger  *logger.Logger
+	metrics *ReloadMetrics
+
+	coordinator *Coordinator[T]
+	applyMu     sync.Mutex
+
+	mu          sync.Mutex
+	history     []Snapshot[T]
+	historySize int
+}
+
+// NewStore loads the configuration and keeps the returned Store current by watching the
+// source until ctx is cancelled. Options are passed through to Watch.
+func NewStore[T any](ctx context.Context, logger *logger.Logger, opts ...Option) (*Store[T], error) {
+	settings := newOptions(opts)
+	metrics := settings.metrics
+
+	updates, watchErr := Watch[T](ctx, logger, append(opts, WithReloadMetrics(metrics), withAwaitApply())...)
+	if watchErr != nil {
+		return nil, watchErr
+	}
+
+	initial, ok := <-updates
+	if !ok {
+		return nil, fmt.Errorf("configuration store stopped before loading: %w", ctx.Err())
+	}
+
+	store := &Store[T]{
+		logger:      logger,
+		metrics:     metrics,
+		coordinator: NewCoordinator[T](),
+		historySize: max(settings.historySize, 1),
+	}
+	store.swap(initial)
+
+	go store.run(updates)
+
+	return store, nil
+}
+
+// Load returns the current configuration snapshot. Callers must treat it as read-only;
+// it is replaced, never mutated, when the configuration changes.
+func (s *Store[T]) Load() *T {
+	return s.current.Load()
+}
+
+// Stats returns the reload counters of the Store's watcher.
+func (s *Store[T]) Stats() ReloadStats {
+	return s.metrics.Stats()
+}
+
+// Register adds a participant that prepares for and commits every subsequent
+// configuration change, including rollbacks. A change that a participant fails to
+// prepare for is not applied.
+func (s *Store[T]) Register(participant Participant[T]) {
+	s.coordinator.Register(participant)
+}
+
+// Snapshots returns the retained snapshots, newest (current) first.
+func (s *Store[T]) Snapshots() []Snapshot[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Snapshot[T](nil), s.history...)
+}
+
+// Rollback makes the snapshot n steps back the current configuration and discards the
+// newer ones; Rollback(1) reverts to the previous configuration. The rolled-back
+// configuration stays current until the source changes again.
+func (s *Store[T]) Rollback(n int) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+
+	if n < 1 || n >= len(s.history) {
+		s.mu.Unlock()
+
+		return fmt.Errorf("%w: %d of %d", ErrNoSnapshot, n, len(s.history)-1)
+	}
+
+	target := s.history[n]
+	s.mu.Unlock()
+
+	return s.coordinator.Apply(context.Background(), target.Config, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.history = s.history[n:]
+		s.current.Store(target.Config)
+	})
+}
+
+// run applies every successfully loaded configuration until updates is closed.
+func (s *Store[T]) run(updates <-chan Update[T]) {
+	for update := range updates {
+		if update.Err != nil {
+			s.logger.Error("failed to reload configuration, keeping previous: %v", update.Err)
+
+			continue
+		}
+
+		s.apply(update)
+	}
+}
+
+// apply swaps in update once every registered participant has prepared for it, and
+// reports the outcome back to the watcher.
+func (s *Store[T]) apply(update Update[T]) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	applyErr := s.coordinator.Apply(context.Background(), update.Config, func() {
+		s.swap(update)
+	})
+	if applyErr != nil {
+		s.logger.Error("failed to apply configuration, keeping previous: %v", applyErr)
+	}
+
+	if update.applied != nil {
+		update.applied <- applyErr
+	}
+}
+
+// swap records update as the newest snapshot and makes it current.
+func (s *Store[T]) swap(update Update[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := Snapshot[T]{
+		Config:   update.Config,
+		Hash:     update.Hash,
+		Source:   update.Source,
+		LoadedAt: update.LoadedAt,
+	}
+
+	s.history = append([]Snapshot[T]{snapshot}, s.history...)
+	if len(s.history) > s.historySize {
+		s.history = s.history[:s.historySize]
+	}
+
+	s.current.Store(update.Config)
+}
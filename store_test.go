@@ -0,0 +1,198 @@
+package configurator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// eventually bounds how long the tests wait for a background reload.
+	eventually = 5 * time.Second
+	// tick is how often the tests check whether a background reload happened.
+	tick = 10 * time.Millisecond
+)
+
+// mutableServer serves a project.toml that tests can replace.
+type mutableServer struct {
+	*httptest.Server
+	document atomic.Value
+}
+
+// newMutableServer starts a mutableServer serving document and points PROJECT_TOML at it.
+func newMutableServer(t *testing.T, document string) *mutableServer {
+	t.Helper()
+
+	server := &mutableServer{}
+	server.document.Store(document)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		document, _ := server.document.Load().(string)
+		_, _ = w.Write([]byte(document))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	return server
+}
+
+// newTestStore returns a Store reloading only when trigger fires.
+func newTestStore(t *testing.T, trigger *ReloadTrigger, opts ...Option) *Store[testConfig] {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts = append([]Option{WithPollInterval(time.Hour), WithReloadTrigger(trigger)}, opts...)
+
+	store, storeErr := NewStore[testConfig](ctx, newTestLogger(t), opts...)
+	require.NoError(t, storeErr)
+
+	return store
+}
+
+// reloadTo replaces the served document, forces a reload, and waits until the store
+// holds a configuration named name.
+func reloadTo(t *testing.T, server *mutableServer, trigger *ReloadTrigger, store *Store[testConfig], name string) {
+	t.Helper()
+
+	server.document.Store("[service]\nname = \"" + name + "\"\n")
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return store.Load().Service.Name == name
+	}, eventually, tick)
+}
+
+func TestStoreSwapsInChangedConfiguration(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"first\"\n")
+	trigger := NewReloadTrigger()
+	store := newTestStore(t, trigger)
+
+	initial := store.Load()
+	assert.Equal(t, "first", initial.Service.Name)
+
+	reloadTo(t, server, trigger, store, "second")
+
+	assert.Equal(t, "first", initial.Service.Name, "snapshots are replaced, never mutated")
+
+	snapshots := store.Snapshots()
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "second", snapshots[0].Config.Service.Name)
+	assert.Equal(t, contentHash([]byte("[service]\nname = \"second\"\n")), snapshots[0].Hash)
+	assert.Equal(t, server.URL+"/project.toml", snapshots[0].Source)
+	assert.Equal(t, "first", snapshots[1].Config.Service.Name)
+
+	require.Eventually(t, func() bool {
+		return store.Stats().Successes == 1
+	}, eventually, tick)
+	assert.Equal(t, uint64(1), store.Stats().Attempts, "the initial load is not a reload")
+	assert.Zero(t, store.Stats().Failures)
+}
+
+func TestStoreKeepsPreviousConfigurationWhenReloadFails(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"good\"\n")
+	trigger := NewReloadTrigger()
+	store := newTestStore(t, trigger)
+
+	server.document.Store("[service\nname = ")
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return store.Stats().Failures == 1
+	}, eventually, tick)
+	assert.Equal(t, "good", store.Load().Service.Name)
+	assert.Len(t, store.Snapshots(), 1)
+	require.Error(t, store.Stats().LastError)
+	assert.Equal(t, uint64(1), store.Stats().Attempts)
+	assert.Zero(t, store.Stats().Successes)
+}
+
+func TestStoreRollback(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"v1\"\n")
+	trigger := NewReloadTrigger()
+	store := newTestStore(t, trigger, WithHistorySize(3))
+
+	for _, name := range []string{"v2", "v3", "v4"} {
+		reloadTo(t, server, trigger, store, name)
+	}
+
+	assert.Len(t, store.Snapshots(), 3, "history is capped at its size")
+
+	require.ErrorIs(t, store.Rollback(0), ErrNoSnapshot)
+	require.ErrorIs(t, store.Rollback(3), ErrNoSnapshot)
+
+	var committed []string
+
+	store.Register(Participant[testConfig]{
+		Name:   "recorder",
+		Commit: func(next *testConfig) { committed = append(committed, next.Service.Name) },
+	})
+
+	require.NoError(t, store.Rollback(2))
+	assert.Equal(t, "v2", store.Load().Service.Name)
+	assert.Len(t, store.Snapshots(), 1)
+	assert.Equal(t, []string{"v2"}, committed)
+}
+
+func TestStoreRollbackRespectsParticipants(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"v1\"\n")
+	trigger := NewReloadTrigger()
+	store := newTestStore(t, trigger)
+
+	reloadTo(t, server, trigger, store, "v2")
+
+	store.Register(Participant[testConfig]{
+		Name:    "refuser",
+		Prepare: func(context.Context, *testConfig) error { return context.Canceled },
+	})
+
+	require.ErrorIs(t, store.Rollback(1), ErrReloadAborted)
+	assert.Equal(t, "v2", store.Load().Service.Name)
+	assert.Len(t, store.Snapshots(), 2)
+}
+
+func TestStoreCountsAbortedChangesAndOffersThemAgain(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"old\"\n")
+	trigger := NewReloadTrigger()
+	store := newTestStore(t, trigger)
+
+	var refuse atomic.Bool
+
+	refuse.Store(true)
+	store.Register(Participant[testConfig]{
+		Name: "draining",
+		Prepare: func(context.Context, *testConfig) error {
+			if refuse.Load() {
+				return context.DeadlineExceeded
+			}
+
+			return nil
+		},
+	})
+
+	server.document.Store("[service]\nname = \"new\"\n")
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return store.Stats().Failures == 1
+	}, eventually, tick)
+	require.ErrorIs(t, store.Stats().LastError, ErrReloadAborted)
+	assert.Equal(t, "old", store.Load().Service.Name)
+
+	refuse.Store(false)
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return store.Load().Service.Name == "new"
+	}, eventually, tick)
+	require.Eventually(t, func() bool {
+		return store.Stats().Successes == 1
+	}, eventually, tick)
+	assert.Equal(t, uint64(2), store.Stats().Attempts)
+}
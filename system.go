@@ -0,0 +1,87 @@
+package configurator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultSystemConfigFile is the machine-wide configuration WithMachineConfig merges
+	// beneath the project.
+	DefaultSystemConfigFile = "/etc/book-expert/project.toml"
+	// userConfigDirectory is the directory below the user's configuration directory that
+	// holds the per-user configuration.
+	userConfigDirectory = "book-expert"
+	// userConfigFileName is the name of the per-user configuration file.
+	userConfigFileName = "project.toml"
+)
+
+// WithMachineConfig enables or disables merging DefaultSystemConfigFile and
+// ~/.config/book-expert/project.toml, or the platform equivalent, beneath the project
+// as LayerSystem and LayerUser. It is disabled by default, so files outside the project
+// do not change a service's configuration unless it opts in.
+func WithMachineConfig(enabled bool) Option {
+	return func(o *options) {
+		o.systemConfigFile, o.userConfigFile = "", ""
+		if enabled {
+			o.systemConfigFile, o.userConfigFile = DefaultSystemConfigFile, defaultUserConfigFile()
+		}
+	}
+}
+
+// WithSystemConfigFile sets the machine-wide configuration file merged as LayerSystem.
+// An empty path disables the layer.
+func WithSystemConfigFile(path string) Option {
+	return func(o *options) {
+		o.systemConfigFile = path
+	}
+}
+
+// WithUserConfigFile sets the per-user configuration file merged as LayerUser. An empty
+// path disables the layer.
+func WithUserConfigFile(path string) Option {
+	return func(o *options) {
+		o.userConfigFile = path
+	}
+}
+
+// defaultUserConfigFile returns ~/.config/book-expert/project.toml, or the platform
+// equivalent, and an empty path when the user configuration directory is unknown.
+func defaultUserConfigFile() string {
+	configDir, configDirErr := os.UserConfigDir()
+	if configDirErr != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, userConfigDirectory, userConfigFileName)
+}
+
+// collectMachineConfig reads the system-wide and per-user configuration files as the
+// LayerSystem and LayerUser layers. Missing files are ignored.
+func collectMachineConfig(state *assembly) error {
+	layers := []struct {
+		layer Layer
+		path  string
+	}{
+		{LayerSystem, state.settings.systemConfigFile},
+		{LayerUser, state.settings.userConfigFile},
+	}
+
+	for _, candidate := range layers {
+		if candidate.path == "" {
+			continue
+		}
+
+		table, found, readErr := state.readLayer(candidate.path)
+		if readErr != nil {
+			return fmt.Errorf("failed to load %s configuration: %w", candidate.layer, readErr)
+		}
+
+		if found {
+			state.setLayer(candidate.layer, table)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMergesMachineConfigBeneathProject(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.toml")
+	user := filepath.Join(dir, "user.toml")
+	require.NoError(t, os.WriteFile(system, []byte("[service]\nname = \"system\"\nport = 1\n[nats]\nurl = \"nats://system\"\n"), 0o600))
+	require.NoError(t, os.WriteFile(user, []byte("[service]\nport = 2\n[nats]\nurl = \"nats://user\"\n"), 0o600))
+	localDocument(t, "[service]\nport = 8080\n")
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithSystemConfigFile(system), WithUserConfigFile(user)))
+	assert.Equal(t, map[string]any{"name": "system", "port": int64(8080)}, config["service"])
+	assert.Equal(t, map[string]any{"url": "nats://user"}, config["nats"])
+}
+
+func TestLoadSkipsMissingMachineConfig(t *testing.T) {
+	localDocument(t, "[service]\nport = 8080\n")
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, newTestLogger(t),
+		WithSystemConfigFile(filepath.Join(t.TempDir(), "missing.toml")), WithUserConfigFile("")))
+	assert.Equal(t, 8080, config.Service.Port)
+}
+
+func TestWithMachineConfigTogglesDefaultFiles(t *testing.T) {
+	t.Parallel()
+
+	enabled := newOptions([]Option{WithMachineConfig(true)})
+	assert.Equal(t, DefaultSystemConfigFile, enabled.systemConfigFile)
+	assert.Equal(t, defaultUserConfigFile(), enabled.userConfigFile)
+
+	disabled := newOptions([]Option{WithMachineConfig(true), WithMachineConfig(false)})
+	assert.Empty(t, disabled.systemConfigFile)
+	assert.Empty(t, disabled.userConfigFile)
+
+	assert.Empty(t, newOptions(nil).systemConfigFile, "machine configuration is opt-in")
+}
@@ -0,0 +1,185 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// templatesTable is the top-level table holding the reusable blocks.
+	templatesTable = "templates"
+	// extendsKey names the blocks a table is based on. The leading underscore keeps it
+	// apart from ordinary settings named extends.
+	extendsKey = "_extends"
+)
+
+var (
+	// ErrUnknownTemplate is returned when _extends names a table that does not exist.
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrTemplateCycle is returned when templates extend each other in a cycle.
+	ErrTemplateCycle = errors.New("template cycle detected")
+	// ErrInvalidExtends is returned when _extends is not a string or a list of strings.
+	ErrInvalidExtends = errors.New("_extends must be a string or a list of strings")
+)
+
+// resolveTemplates expands the _extends keys of the effective configuration: a table
+// with _extends = "templates.http-defaults" is merged over that table, and a list of
+// references is merged in order. The [templates] table is removed from the result.
+// The boolean result reports whether the configuration used any templates.
+func resolveTemplates(root map[string]any, mergeOptions MergeOptions) (map[string]any, bool, error) {
+	resolver := &templateResolver{
+		root:         root,
+		mergeOptions: mergeOptions,
+		resolved:     make(map[string]map[string]any),
+	}
+
+	resolved, resolveErr := resolver.table("", withoutKey(root, templatesTable), nil)
+	if resolveErr != nil {
+		return nil, false, resolveErr
+	}
+
+	_, hasTemplates := root[templatesTable]
+
+	return resolved, resolver.found || hasTemplates, nil
+}
+
+// templateResolver expands _extends keys against root, caching each resolved template.
+// found records whether any _extends key was seen.
+type templateResolver struct {
+	root         map[string]any
+	mergeOptions MergeOptions
+	resolved     map[string]map[string]any
+	found        bool
+}
+
+// table returns table, and the tables nested in it, merged over the templates they
+// extend. chain holds the templates currently being resolved and is used to detect
+// cycles.
+func (r *templateResolver) table(path string, table map[string]any, chain []string) (map[string]any, error) {
+	own := make(map[string]any, len(table))
+
+	for key, value := range table {
+		if key == extendsKey {
+			continue
+		}
+
+		resolved, valueErr := r.value(joinKey(path, key), value, chain)
+		if valueErr != nil {
+			return nil, valueErr
+		}
+
+		own[key] = resolved
+	}
+
+	raw, extends := table[extendsKey]
+	if !extends {
+		return own, nil
+	}
+
+	r.found = true
+
+	references, listErr := extendsList(raw)
+	if listErr != nil {
+		return nil, fmt.Errorf("%s: %w", path, listErr)
+	}
+
+	var base map[string]any
+
+	for _, reference := range references {
+		template, templateErr := r.template(reference, chain)
+		if templateErr != nil {
+			return nil, fmt.Errorf("%s: %w", path, templateErr)
+		}
+
+		base = Merge(base, template, r.mergeOptions)
+	}
+
+	return Merge(base, own, r.mergeOptions), nil
+}
+
+// value resolves the tables within value, which may be a table or an array of tables.
+func (r *templateResolver) value(path string, value any, chain []string) (any, error) {
+	switch typed := value.(type) {
+	case map[string]any:
+		return r.table(path, typed, chain)
+	case []any:
+		elements := make([]any, len(typed))
+
+		for index, element := range typed {
+			resolved, elementErr := r.value(fmt.Sprintf("%s[%d]", path, index), element, chain)
+			if elementErr != nil {
+				return nil, elementErr
+			}
+
+			elements[index] = resolved
+		}
+
+		return elements, nil
+	default:
+		return value, nil
+	}
+}
+
+// template returns the table at the dotted path reference, with its own _extends
+// resolved.
+func (r *templateResolver) template(reference string, chain []string) (map[string]any, error) {
+	if slices.Contains(chain, reference) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrTemplateCycle, strings.Join(chain, " -> "), reference)
+	}
+
+	if resolved, cached := r.resolved[reference]; cached {
+		return resolved, nil
+	}
+
+	var current any = r.root
+
+	for key := range strings.SplitSeq(reference, ".") {
+		table, isTable := current.(map[string]any)
+		if !isTable {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, reference)
+		}
+
+		current = table[key]
+	}
+
+	table, isTable := current.(map[string]any)
+	if !isTable {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, reference)
+	}
+
+	resolved, resolveErr := r.table(reference, table, append(slices.Clone(chain), reference))
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	r.resolved[reference] = resolved
+
+	return resolved, nil
+}
+
+// extendsList returns the references named by an _extends value.
+func extendsList(raw any) ([]string, error) {
+	if reference, isString := raw.(string); isString {
+		return []string{reference}, nil
+	}
+
+	values, isList := raw.([]any)
+	if !isList {
+		return nil, ErrInvalidExtends
+	}
+
+	references := make([]string, 0, len(values))
+
+	for _, value := range values {
+		reference, isString := value.(string)
+		if !isString {
+			return nil, ErrInvalidExtends
+		}
+
+		references = append(references, reference)
+	}
+
+	return references, nil
+}
@@ -0,0 +1,149 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expandTemplates parses document and resolves its templates.
+func expandTemplates(t *testing.T, document string) (map[string]any, error) {
+	t.Helper()
+
+	var root map[string]any
+
+	require.NoError(t, toml.Unmarshal([]byte(document), &root))
+
+	resolved, _, resolveErr := resolveTemplates(root, MergeOptions{})
+
+	return resolved, resolveErr
+}
+
+func TestLoadExpandsTemplateChains(t *testing.T) {
+	localDocument(t, `[templates.base]
+timeout = "5s"
+retries = 3
+tls = true
+
+[templates.http]
+_extends = "templates.base"
+timeout = "10s"
+
+[templates.metrics]
+path = "/metrics"
+retries = 1
+
+[api]
+_extends = ["templates.http", "templates.metrics"]
+retries = 5
+
+[[workers]]
+_extends = "templates.base"
+name = "first"
+`)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, map[string]any{
+		"timeout": "10s", "retries": int64(5), "tls": true, "path": "/metrics",
+	}, config["api"])
+	assert.Equal(t, []any{map[string]any{
+		"timeout": "5s", "retries": int64(3), "tls": true, "name": "first",
+	}}, config["workers"])
+	assert.NotContains(t, config, "templates")
+}
+
+func TestResolveTemplatesMergesListsInOrder(t *testing.T) {
+	t.Parallel()
+
+	resolved, resolveErr := expandTemplates(t, `[templates.a]
+value = "a"
+only_a = true
+
+[templates.b]
+value = "b"
+
+[service]
+_extends = ["templates.a", "templates.b"]
+`)
+	require.NoError(t, resolveErr)
+	assert.Equal(t, map[string]any{"value": "b", "only_a": true}, resolved["service"])
+}
+
+func TestResolveTemplatesRejectsBadReferences(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		document string
+		want     error
+	}{
+		"self reference": {
+			document: "[templates.a]\n_extends = \"templates.a\"\n[service]\n_extends = \"templates.a\"\n",
+			want:     ErrTemplateCycle,
+		},
+		"longer cycle": {
+			document: `[templates.a]
+_extends = "templates.b"
+[templates.b]
+_extends = "templates.c"
+[templates.c]
+_extends = "templates.a"
+[service]
+_extends = "templates.a"
+`,
+			want: ErrTemplateCycle,
+		},
+		"missing template": {document: "[service]\n_extends = \"templates.missing\"\n", want: ErrUnknownTemplate},
+		"not a table":      {document: "name = \"x\"\n[service]\n_extends = \"name.inner\"\n", want: ErrUnknownTemplate},
+		"number reference": {document: "[service]\n_extends = 3\n", want: ErrInvalidExtends},
+		"mixed list":       {document: "[templates.a]\nx = 1\n[service]\n_extends = [\"templates.a\", 3]\n", want: ErrInvalidExtends},
+		"cycle through lists": {
+			document: `[templates.a]
+_extends = ["templates.b"]
+[templates.b]
+_extends = ["templates.a"]
+[service]
+_extends = "templates.b"
+`,
+			want: ErrTemplateCycle,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, resolveErr := expandTemplates(t, test.document)
+			require.ErrorIs(t, resolveErr, test.want)
+		})
+	}
+}
+
+func TestResolveTemplatesNamesTheCycle(t *testing.T) {
+	t.Parallel()
+
+	_, resolveErr := expandTemplates(t, `[templates.a]
+_extends = "templates.b"
+[templates.b]
+_extends = "templates.a"
+[service]
+_extends = "templates.a"
+`)
+	require.ErrorIs(t, resolveErr, ErrTemplateCycle)
+	assert.ErrorContains(t, resolveErr, "templates.a -> templates.b -> templates.a")
+}
+
+func TestResolveTemplatesReportsUse(t *testing.T) {
+	t.Parallel()
+
+	var root map[string]any
+
+	require.NoError(t, toml.Unmarshal([]byte("[service]\nname = \"tts\"\n"), &root))
+
+	_, used, resolveErr := resolveTemplates(root, MergeOptions{})
+	require.NoError(t, resolveErr)
+	assert.False(t, used)
+}
@@ -0,0 +1,93 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Conflict is a key changed differently by both sides of a three-way merge. A nil
+// value means the key is absent on that side.
+type Conflict struct {
+	Key    string
+	Base   any
+	Ours   any
+	Theirs any
+}
+
+// String describes the conflict for a conflict report.
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: base=%v ours=%v theirs=%v", c.Key, c.Base, c.Ours, c.Theirs)
+}
+
+// ThreeWayMerge combines two edits, ours and theirs, of the common ancestor base. A key
+// changed on only one side takes that side's value, including removal; tables are
+// merged key by key. Keys changed differently on both sides keep our value and are
+// reported as conflicts, sorted by key.
+func ThreeWayMerge(base, ours, theirs map[string]any) (map[string]any, []Conflict) {
+	var conflicts []Conflict
+
+	merged := mergeThreeWay("", base, ours, theirs, &conflicts)
+
+	slices.SortFunc(conflicts, func(a, b Conflict) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return merged, conflicts
+}
+
+// mergeThreeWay merges the tables below prefix, appending conflicts.
+func mergeThreeWay(prefix string, base, ours, theirs map[string]any, conflicts *[]Conflict) map[string]any {
+	merged := make(map[string]any)
+
+	for _, key := range unionKeys(base, ours, theirs) {
+		path := joinKey(prefix, key)
+		baseValue, oursValue, theirsValue := base[key], ours[key], theirs[key]
+
+		baseTable, baseIsTable := baseValue.(map[string]any)
+		oursTable, oursIsTable := oursValue.(map[string]any)
+		theirsTable, theirsIsTable := theirsValue.(map[string]any)
+
+		if oursIsTable && theirsIsTable && (baseIsTable || baseValue == nil) {
+			merged[key] = mergeThreeWay(path, baseTable, oursTable, theirsTable, conflicts)
+
+			continue
+		}
+
+		var value any
+
+		switch {
+		case reflect.DeepEqual(oursValue, theirsValue):
+			value = oursValue
+		case reflect.DeepEqual(baseValue, oursValue):
+			value = theirsValue
+		case reflect.DeepEqual(baseValue, theirsValue):
+			value = oursValue
+		default:
+			value = oursValue
+			*conflicts = append(*conflicts, Conflict{Key: path, Base: baseValue, Ours: oursValue, Theirs: theirsValue})
+		}
+
+		if value != nil {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// unionKeys returns the keys present in any of the tables.
+func unionKeys(tables ...map[string]any) []string {
+	var keys []string
+
+	for _, table := range tables {
+		for key := range table {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,108 @@
+package configurator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreeWayMergeTakesOneSidedChanges(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{"port": int64(80), "host": "a", "debug": true, "workers": []any{"x"}}
+	ours := map[string]any{"port": int64(8080), "host": "a", "debug": true, "workers": []any{"x"}}
+	theirs := map[string]any{"port": int64(80), "host": "b", "workers": []any{"x", "y"}, "added": "new"}
+
+	merged, conflicts := ThreeWayMerge(base, ours, theirs)
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, map[string]any{
+		"port":    int64(8080),
+		"host":    "b",
+		"workers": []any{"x", "y"},
+		"added":   "new",
+	}, merged)
+}
+
+func TestThreeWayMergeAcceptsIdenticalChanges(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{"port": int64(80), "gone": "x"}
+	ours := map[string]any{"port": int64(443), "both": "same"}
+	theirs := map[string]any{"port": int64(443), "both": "same"}
+
+	merged, conflicts := ThreeWayMerge(base, ours, theirs)
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, map[string]any{"port": int64(443), "both": "same"}, merged)
+}
+
+func TestThreeWayMergeMergesNestedTables(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{"nats": map[string]any{"url": "nats://a", "timeout": "1s"}}
+	ours := map[string]any{"nats": map[string]any{"url": "nats://b", "timeout": "1s"}}
+	theirs := map[string]any{"nats": map[string]any{"url": "nats://a", "timeout": "5s", "tls": map[string]any{"cert": "c"}}}
+
+	merged, conflicts := ThreeWayMerge(base, ours, theirs)
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, map[string]any{"nats": map[string]any{
+		"url":     "nats://b",
+		"timeout": "5s",
+		"tls":     map[string]any{"cert": "c"},
+	}}, merged)
+}
+
+func TestThreeWayMergeMergesTablesAddedOnBothSides(t *testing.T) {
+	t.Parallel()
+
+	ours := map[string]any{"cache": map[string]any{"dir": "/tmp"}}
+	theirs := map[string]any{"cache": map[string]any{"size": int64(10)}}
+
+	merged, conflicts := ThreeWayMerge(map[string]any{}, ours, theirs)
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, map[string]any{"cache": map[string]any{"dir": "/tmp", "size": int64(10)}}, merged)
+}
+
+func TestThreeWayMergeReportsConflictsKeepingOurs(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{
+		"port":  int64(80),
+		"nats":  map[string]any{"url": "nats://a"},
+		"mode":  "table-later",
+		"level": "info",
+	}
+	ours := map[string]any{
+		"port":  int64(8080),
+		"nats":  map[string]any{"url": "nats://b"},
+		"mode":  map[string]any{"kind": "x"},
+		"added": "ours",
+	}
+	theirs := map[string]any{
+		"port":  int64(9090),
+		"nats":  map[string]any{"url": "nats://c"},
+		"mode":  map[string]any{"kind": "y"},
+		"level": "debug",
+		"added": "theirs",
+	}
+
+	merged, conflicts := ThreeWayMerge(base, ours, theirs)
+
+	assert.Equal(t, map[string]any{
+		"port":  int64(8080),
+		"nats":  map[string]any{"url": "nats://b"},
+		"mode":  map[string]any{"kind": "x"},
+		"added": "ours",
+	}, merged)
+	assert.Equal(t, []Conflict{
+		{Key: "added", Base: nil, Ours: "ours", Theirs: "theirs"},
+		{Key: "level", Base: "info", Ours: nil, Theirs: "debug"},
+		{Key: "mode", Base: "table-later", Ours: map[string]any{"kind": "x"}, Theirs: map[string]any{"kind": "y"}},
+		{Key: "nats.url", Base: "nats://a", Ours: "nats://b", Theirs: "nats://c"},
+		{Key: "port", Base: int64(80), Ours: int64(8080), Theirs: int64(9090)},
+	}, conflicts)
+	assert.Equal(t, "port: base=80 ours=8080 theirs=9090", conflicts[4].String())
+}
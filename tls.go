@@ -0,0 +1,89 @@
+package configurator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// ClientCertVariable names the environment variable holding the client certificate
+	// file for mutual TLS, used when WithClientCertificate is not given.
+	ClientCertVariable = "PROJECT_TOML_CLIENT_CERT"
+	// ClientKeyVariable names the environment variable holding the client key file.
+	ClientKeyVariable = "PROJECT_TOML_CLIENT_KEY"
+	// CABundleVariable names the environment variable holding the CA bundle file, used
+	// when WithCABundle is not given.
+	CABundleVariable = "PROJECT_TOML_CA_BUNDLE"
+)
+
+// ErrInvalidCABundle is returned when the CA bundle contains no PEM certificates.
+var ErrInvalidCABundle = errors.New("CA bundle contains no certificates")
+
+// WithClientCertificate presents the PEM certificate and key in certFile and keyFile to
+// configuration servers that require mutual TLS. The files are re-read on every TLS
+// handshake, so rotated certificates are picked up without a restart.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.clientCert = certFile
+		o.clientKey = keyFile
+	}
+}
+
+// WithCABundle trusts the PEM certificates in path, instead of the system roots, when
+// verifying configuration servers.
+func WithCABundle(path string) Option {
+	return func(o *options) {
+		o.caBundle = path
+	}
+}
+
+// usesCustomTLS reports whether settings configure a client certificate or CA bundle.
+func (o *options) usesCustomTLS() bool {
+	return o.clientCert != "" || o.caBundle != ""
+}
+
+// tlsConfig returns base, or a default configuration when it is nil, with the client
+// certificate and CA bundle applied.
+func (o *options) tlsConfig(base *tls.Config) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if o.caBundle != "" {
+		bundle, readErr := os.ReadFile(o.caBundle)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", o.caBundle, readErr)
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCABundle, o.caBundle)
+		}
+
+		config.RootCAs = roots
+	}
+
+	if o.clientCert != "" {
+		certFile, keyFile := o.clientCert, o.clientKey
+
+		_, loadErr := tls.LoadX509KeyPair(certFile, keyFile)
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", loadErr)
+		}
+
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, reloadErr := tls.LoadX509KeyPair(certFile, keyFile)
+			if reloadErr != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", reloadErr)
+			}
+
+			return &certificate, nil
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,73 @@
+package configurator
+
+import (
+	"os"
+	"path"
+	"runtime/debug"
+)
+
+const (
+	// UserAgentVariable names the environment variable holding the User-Agent sent with
+	// configuration requests, used when WithUserAgent is not given.
+	UserAgentVariable = "PROJECT_TOML_USER_AGENT"
+	// modulePath is the module path of this package, used to find its version.
+	modulePath = "github.com/book-expert/configurator"
+	// libraryProduct names this package in User-Agent headers.
+	libraryProduct = "book-expert-configurator"
+	// develVersion is the version reported for builds without module version information.
+	develVersion = "devel"
+)
+
+// WithUserAgent identifies the fetching service in the User-Agent of configuration
+// requests, e.g. WithUserAgent("tts", "1.4.0") sends "tts/1.4.0
+// book-expert-configurator/<version>", so the configuration host's logs tell which
+// service fetched what.
+func WithUserAgent(service, version string) Option {
+	return func(o *options) {
+		o.userAgent = service + "/" + version + " " + libraryAgent()
+	}
+}
+
+// defaultUserAgent returns the User-Agent from UserAgentVariable or, failing that, one
+// naming the main module of the running binary and its version.
+func defaultUserAgent() string {
+	if agent := os.Getenv(UserAgentVariable); agent != "" {
+		return agent
+	}
+
+	info, hasInfo := debug.ReadBuildInfo()
+	if !hasInfo || info.Main.Path == "" || info.Main.Path == modulePath {
+		return libraryAgent()
+	}
+
+	return path.Base(info.Main.Path) + "/" + moduleVersion(info.Main) + " " + libraryAgent()
+}
+
+// libraryAgent returns the User-Agent product token of this package.
+func libraryAgent() string {
+	info, hasInfo := debug.ReadBuildInfo()
+	if !hasInfo {
+		return libraryProduct + "/" + develVersion
+	}
+
+	for _, dependency := range info.Deps {
+		if dependency.Path == modulePath {
+			return libraryProduct + "/" + moduleVersion(*dependency)
+		}
+	}
+
+	return libraryProduct + "/" + develVersion
+}
+
+// moduleVersion returns the version of module, or develVersion for local builds.
+func moduleVersion(module debug.Module) string {
+	if module.Replace != nil {
+		module = *module.Replace
+	}
+
+	if module.Version == "" || module.Version == "(devel)" {
+		return develVersion
+	}
+
+	return module.Version
+}
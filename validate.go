@@ -0,0 +1,43 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidConfig is returned when a loaded configuration fails validation.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Validator is implemented by configuration types that can check their own consistency.
+// Watch calls Validate on every decoded configuration before publishing it.
+type Validator interface {
+	Validate() error
+}
+
+// WithValidation adds a validation function that Watch runs, after the target's own
+// Validate method, on every decoded configuration before publishing it.
+func WithValidation(validate func(config any) error) Option {
+	return func(o *options) {
+		o.validations = append(o.validations, validate)
+	}
+}
+
+// validateConfig runs the target's Validate method, when it has one, followed by the
+// configured validation functions, and wraps the first failure in ErrInvalidConfig.
+func validateConfig(target any, settings *options) error {
+	if validator, ok := target.(Validator); ok {
+		validateErr := validator.Validate()
+		if validateErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, validateErr)
+		}
+	}
+
+	for _, validate := range settings.validations {
+		validateErr := validate(target)
+		if validateErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, validateErr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// errNoPort is returned by validatedConfig without a port.
+	errNoPort = errors.New("port is required")
+	// errUnprivilegedPort is returned by the validation function of the tests.
+	errUnprivilegedPort = errors.New("unprivileged port")
+)
+
+// validatedConfig is a testConfig that requires a port.
+type validatedConfig struct {
+	testConfig
+}
+
+// Validate requires a port.
+func (c *validatedConfig) Validate() error {
+	if c.Service.Port == 0 {
+		return errNoPort
+	}
+
+	return nil
+}
+
+func TestWatchRejectsInvalidInitialConfiguration(t *testing.T) {
+	newMutableServer(t, "[service]\nname = \"tts\"\n")
+
+	_, watchErr := Watch[validatedConfig](context.Background(), newTestLogger(t))
+	require.ErrorIs(t, watchErr, ErrInvalidConfig)
+	require.ErrorIs(t, watchErr, errNoPort)
+}
+
+func TestWatchKeepsPublishingAfterInvalidReloads(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"tts\"\nport = 8080\n")
+	trigger := NewReloadTrigger()
+	metrics := NewReloadMetrics()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	updates, watchErr := Watch[validatedConfig](ctx, newTestLogger(t),
+		WithPollInterval(time.Hour), WithReloadTrigger(trigger), WithReloadMetrics(metrics))
+	require.NoError(t, watchErr)
+
+	next := func() Update[validatedConfig] {
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(eventually):
+			require.FailNow(t, "no update published")
+
+			return Update[validatedConfig]{}
+		}
+	}
+
+	assert.Equal(t, 8080, next().Config.Service.Port)
+
+	server.document.Store("[service]\nname = \"tts\"\n")
+	trigger.Trigger()
+
+	rejected := next()
+	require.ErrorIs(t, rejected.Err, ErrInvalidConfig)
+	assert.Nil(t, rejected.Config)
+
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return metrics.Stats().Failures == 2
+	}, eventually, tick)
+
+	select {
+	case update := <-updates:
+		assert.Failf(t, "rejected configuration published again", "%+v", update)
+	case <-time.After(quiet):
+	}
+
+	server.document.Store("[service]\nname = \"tts\"\nport = 443\n")
+	trigger.Trigger()
+
+	fixed := next()
+	require.NoError(t, fixed.Err)
+	assert.Equal(t, 443, fixed.Config.Service.Port)
+}
+
+func TestWithValidationRunsAfterValidate(t *testing.T) {
+	newMutableServer(t, "[service]\nname = \"tts\"\nport = 8080\n")
+
+	var calls []string
+
+	_, watchErr := Watch[validatedConfig](context.Background(), newTestLogger(t),
+		WithValidation(func(config any) error {
+			calls = append(calls, "first")
+
+			typed, _ := config.(*validatedConfig)
+			if typed.Service.Port < 1024 {
+				return nil
+			}
+
+			return errUnprivilegedPort
+		}),
+		WithValidation(func(any) error {
+			calls = append(calls, "second")
+
+			return nil
+		}))
+	require.ErrorIs(t, watchErr, ErrInvalidConfig)
+	require.ErrorIs(t, watchErr, errUnprivilegedPort)
+	assert.Equal(t, []string{"first"}, calls)
+}
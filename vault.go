@@ -0,0 +1,177 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+const (
+	// VaultAddressVariable names the environment variable holding the Vault address.
+	VaultAddressVariable = "VAULT_ADDR"
+	// VaultTokenVariable names the environment variable holding the Vault token.
+	VaultTokenVariable = "VAULT_TOKEN"
+	// VaultNamespaceVariable names the environment variable holding the optional Vault
+	// Enterprise namespace.
+	VaultNamespaceVariable = "VAULT_NAMESPACE"
+	// vaultPrefix starts a value that refers to a Vault secret.
+	vaultPrefix = "vault://"
+)
+
+var (
+	// ErrVaultNotConfigured is returned when a vault:// reference is loaded but
+	// VAULT_ADDR or VAULT_TOKEN is not set.
+	ErrVaultNotConfigured = errors.New("vault address or token not set")
+	// ErrSecretNotFound is returned when a secret reference names a missing secret or field.
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrInvalidSecretReference is returned when a secret reference is malformed.
+	ErrInvalidSecretReference = errors.New("invalid secret reference")
+)
+
+// vaultResolver resolves vault://<path>#<field> references with the Vault HTTP API,
+// reading each secret once per load.
+type vaultResolver struct {
+	logger  *logger.Logger
+	client  *http.Client
+	ctx     context.Context
+	timeout time.Duration
+
+	mu      sync.Mutex
+	secrets map[string]func() (map[string]any, error)
+}
+
+// newVaultResolver returns a resolver with an empty secret cache that makes its requests
+// with the client and timeout of settings.
+func newVaultResolver(settings *options, logger *logger.Logger) *vaultResolver {
+	client := settings.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &vaultResolver{
+		logger:  logger,
+		secrets: make(map[string]func() (map[string]any, error)),
+		client:  client,
+		ctx:     settings.loadContext,
+		timeout: settings.urlTimeout,
+	}
+}
+
+// resolve returns the value of the field a vault:// reference names. The boolean
+// result is false when value is not a vault:// reference.
+func (r *vaultResolver) resolve(value string) (any, bool, error) {
+	reference, isVault := strings.CutPrefix(value, vaultPrefix)
+	if !isVault {
+		return nil, false, nil
+	}
+
+	path, field, hasField := strings.Cut(reference, "#")
+	if !hasField || path == "" || field == "" {
+		return nil, true, fmt.Errorf("%w: %s", ErrInvalidSecretReference, value)
+	}
+
+	secret, readErr := r.read(path)
+	if readErr != nil {
+		return nil, true, readErr
+	}
+
+	resolved, found := secret[field]
+	if !found {
+		return nil, true, fmt.Errorf("%w: %s", ErrSecretNotFound, value)
+	}
+
+	return resolved, true, nil
+}
+
+// read returns the data of the secret at path, fetching it on first use. Concurrent
+// reads of the same path share one request.
+func (r *vaultResolver) read(path string) (map[string]any, error) {
+	r.mu.Lock()
+
+	read, started := r.secrets[path]
+	if !started {
+		read = sync.OnceValues(func() (map[string]any, error) {
+			return r.fetch(path)
+		})
+		r.secrets[path] = read
+	}
+
+	r.mu.Unlock()
+
+	return read()
+}
+
+// fetch reads the data of the secret at path from Vault. KV version 2 secrets, whose
+// data is nested under a second data key, are unwrapped, and whole numbers are kept as
+// integers.
+func (r *vaultResolver) fetch(path string) (map[string]any, error) {
+	address, token := os.Getenv(VaultAddressVariable), os.Getenv(VaultTokenVariable)
+	if address == "" || token == "" {
+		return nil, ErrVaultNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	secretURL := strings.TrimSuffix(address, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+
+	req, newRequestErr := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if newRequestErr != nil {
+		return nil, fmt.Errorf("failed to create Vault request: %w", newRequestErr)
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+
+	if namespace := os.Getenv(VaultNamespaceVariable); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, doRequestErr := r.client.Do(req)
+	if doRequestErr != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %s: %w", path, doRequestErr)
+	}
+
+	defer func() {
+		closeErr := resp.Body.Close()
+		if closeErr != nil {
+			r.logger.Error("failed to close response body: %v", closeErr)
+		}
+	}()
+
+	body, processResponseErr := processResponse(resp, DefaultMaxBodySize)
+	if errors.Is(processResponseErr, ErrSourceNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+	}
+
+	if processResponseErr != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %s: %w", path, processResponseErr)
+	}
+
+	var envelope struct {
+		Data map[string]any `json:"data"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	decodeErr := decoder.Decode(&envelope)
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode Vault secret %s: %w", path, decodeErr)
+	}
+
+	secret, _ := normalizeJSON(envelope.Data).(map[string]any)
+	if nested, isKV2 := secret["data"].(map[string]any); isKV2 {
+		secret = nested
+	}
+
+	return secret, nil
+}
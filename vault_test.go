@@ -0,0 +1,160 @@
+package configurator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultSecrets are the responses of the fake Vault: a KV version 2 secret and a KV
+// version 1 secret.
+var vaultSecrets = map[string]string{
+	"/v1/secret/data/app": `{"data":{"data":{"api_key":"kv2-key","port":8443},"metadata":{"version":3}}}`,
+	"/v1/kv/app":          `{"data":{"password":"kv1-password"}}`,
+}
+
+// vaultServer starts a fake Vault that serves vaultSecrets to requests with the token
+// and namespace, and sets the environment to use it. It returns the number of requests
+// made for each path.
+func vaultServer(t *testing.T) func() map[string]int {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests = make(map[string]int)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.Header.Get("X-Vault-Token") != "root-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+
+			return
+		}
+
+		secret, found := vaultSecrets[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(secret))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv(VaultAddressVariable, server.URL+"/")
+	t.Setenv(VaultTokenVariable, "root-token")
+	t.Setenv(VaultNamespaceVariable, "team")
+
+	return func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requests
+	}
+}
+
+// localDocument writes content to project.toml in a new directory and points
+// PROJECT_TOML at it.
+func localDocument(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "project.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("PROJECT_TOML", path)
+}
+
+func TestLoadResolvesVaultReferences(t *testing.T) {
+	requests := vaultServer(t)
+	localDocument(t, `[api]
+key = "vault://secret/data/app#api_key"
+port = "vault://secret/data/app#port"
+
+[db]
+password = "vault://kv/app#password"
+`)
+
+	var config map[string]any
+
+	require.NoError(t, Load(&config, newTestLogger(t)))
+	assert.Equal(t, map[string]any{"key": "kv2-key", "port": int64(8443)}, config["api"])
+	assert.Equal(t, map[string]any{"password": "kv1-password"}, config["db"])
+	assert.Equal(t, map[string]int{"/v1/secret/data/app": 1, "/v1/kv/app": 1}, requests(), "each secret is read once")
+}
+
+func TestLoadRejectsBadVaultReferences(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		err   error
+	}{
+		"missing field":     {"vault://secret/data/app#missing", ErrSecretNotFound},
+		"missing secret":    {"vault://secret/data/missing#api_key", ErrSecretNotFound},
+		"no field":          {"vault://secret/data/app", ErrInvalidSecretReference},
+		"permission denied": {"vault://secret/data/app#api_key", ErrUnexpectedHTTPStatus},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultServer(t)
+
+			if name == "permission denied" {
+				t.Setenv(VaultTokenVariable, "wrong-token")
+			}
+
+			localDocument(t, "[api]\nkey = \""+test.value+"\"\n")
+
+			var config map[string]any
+
+			loadErr := Load(&config, newTestLogger(t))
+			require.ErrorIs(t, loadErr, test.err)
+
+			var sourceErr *SourceError
+			require.ErrorAs(t, loadErr, &sourceErr)
+			assert.Equal(t, "api.key", sourceErr.Source)
+		})
+	}
+}
+
+func TestLoadRequiresVaultConfiguration(t *testing.T) {
+	vaultServer(t)
+	t.Setenv(VaultTokenVariable, "")
+	localDocument(t, "[api]\nkey = \"vault://secret/data/app#api_key\"\n")
+
+	var config map[string]any
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrVaultNotConfigured)
+}
+
+func TestLoadRejectsVaultReferencesFromUntrustedHosts(t *testing.T) {
+	requests := vaultServer(t)
+	thirdParty := serveDocuments(t, map[string]string{
+		"/shared.toml": "[webhook]\nurl = \"https://attacker.example\"\ntoken = \"vault://secret/data/app#api_key\"\n",
+	})
+	base := serveDocuments(t, map[string]string{
+		"/project.toml": "include = [\"" + thirdParty.URL + "/shared.toml\"]\n[api]\nkey = \"vault://secret/data/app#api_key\"\n",
+	})
+	t.Setenv("PROJECT_TOML", base.URL+"/project.toml")
+
+	var config map[string]any
+
+	require.ErrorIs(t, Load(&config, newTestLogger(t)), ErrUntrustedReference)
+	assert.Empty(t, requests(), "nothing is read from Vault")
+
+	thirdPartyURL, parseErr := url.Parse(thirdParty.URL)
+	require.NoError(t, parseErr)
+
+	require.NoError(t, Load(&config, newTestLogger(t), WithCredentialHosts(thirdPartyURL.Hostname())))
+	assert.Equal(t, map[string]any{"key": "kv2-key"}, config["api"], "the PROJECT_TOML host is trusted")
+	assert.Equal(t, "kv2-key", config["webhook"].(map[string]any)["token"])
+}
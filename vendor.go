@@ -0,0 +1,145 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// vendorMetadataSuffix is appended to the vendored file path to name its metadata file.
+const vendorMetadataSuffix = ".lock"
+
+// WithVendoredCopy makes Load read the vendored copy at path instead of fetching PROJECT_TOML
+// whenever that file exists, enabling reproducible and offline builds.
+func WithVendoredCopy(path string) Option {
+	return func(o *options) {
+		o.vendoredCopy = path
+	}
+}
+
+// Vendor downloads the configuration referenced by PROJECT_TOML into path and records
+// its source, hash, and download time next to it in path + ".lock". Since the document
+// may hold secrets, the copy is encrypted with the key of WithCacheKey, or the secret
+// key, and Vendor fails with ErrCacheKeyNotSet without one unless WithPlaintextCache
+// allows an unencrypted copy. The files are replaced atomically and readable only by
+// their owner. Options customize the fetch as they do for Load. When signatures are
+// required, they are verified and copied next to the vendored copy, so Load can verify
+// it in turn.
+func Vendor(path string, logger *logger.Logger, opts ...Option) error {
+	projectTOMLURL := os.Getenv("PROJECT_TOML")
+	if projectTOMLURL == "" {
+		return ErrProjectTomlNotSet
+	}
+
+	settings := newOptions(opts)
+
+	aead, keyErr := settings.cacheCipher()
+	if keyErr != nil {
+		return fmt.Errorf("failed to vendor %s: %w", path, keyErr)
+	}
+
+	tomlContent, format, fetchErr := readSource(projectTOMLURL, settings, nil, logger)
+	if fetchErr != nil {
+		return fmt.Errorf("failed to fetch TOML from %s: %w", projectTOMLURL, fetchErr)
+	}
+
+	signatures, signaturesErr := readSignatures(settings, projectTOMLURL, tomlContent, nil, logger)
+	if signaturesErr != nil {
+		return signaturesErr
+	}
+
+	sealed, sealErr := sealCopy(aead, tomlContent)
+	if sealErr != nil {
+		return sealErr
+	}
+
+	metadata, marshalErr := toml.Marshal(Lock{
+		Source:    projectTOMLURL,
+		SHA256:    contentHash(tomlContent),
+		FetchedAt: time.Now().UTC(),
+		Format:    format,
+		Sealed:    sealed != "",
+	})
+	if marshalErr != nil {
+		return fmt.Errorf("failed to marshal vendor metadata: %w", marshalErr)
+	}
+
+	stored := tomlContent
+	if sealed != "" {
+		stored = []byte(sealed)
+	}
+
+	writeErr := writeFileAtomic(path, stored)
+	if writeErr != nil {
+		return fmt.Errorf("failed to write vendored copy %s: %w", path, writeErr)
+	}
+
+	writeMetadataErr := writeFileAtomic(path+vendorMetadataSuffix, metadata)
+	if writeMetadataErr != nil {
+		return fmt.Errorf("failed to write vendor metadata %s: %w", path+vendorMetadataSuffix, writeMetadataErr)
+	}
+
+	for suffix, signature := range signatures {
+		writeSignatureErr := writeFileAtomic(path+suffix, signature)
+		if writeSignatureErr != nil {
+			return fmt.Errorf("failed to write signature %s: %w", path+suffix, writeSignatureErr)
+		}
+	}
+
+	return nil
+}
+
+// readVendoredCopy returns the vendored document at path, decrypted with the cache key
+// and verified against its metadata file, and the format recorded there. A copy that is
+// not encrypted, including one without a metadata file, is refused unless plaintext
+// copies are allowed. The boolean result is false when no vendored copy is present.
+func readVendoredCopy(settings *options, path string) ([]byte, string, bool, error) {
+	content, readErr := os.ReadFile(path)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, "", false, nil
+	}
+
+	if readErr != nil {
+		return nil, "", false, fmt.Errorf("failed to read vendored copy %s: %w", path, readErr)
+	}
+
+	metadataPath := path + vendorMetadataSuffix
+
+	var lock Lock
+
+	_, statErr := os.Stat(metadataPath)
+	if statErr == nil {
+		var lockErr error
+
+		lock, lockErr = readLock(metadataPath)
+		if lockErr != nil {
+			return nil, "", false, lockErr
+		}
+	}
+
+	sealed := ""
+	if lock.Sealed {
+		sealed = string(content)
+	}
+
+	opened, openErr := settings.openCopy(sealed, content)
+	if openErr != nil {
+		return nil, "", false, fmt.Errorf("vendored copy %s: %w", path, openErr)
+	}
+
+	if statErr != nil {
+		return opened, "", true, nil
+	}
+
+	verifyErr := verifyLock(metadataPath, opened)
+	if verifyErr != nil {
+		return nil, "", false, fmt.Errorf("vendored copy %s is corrupt: %w", path, verifyErr)
+	}
+
+	return opened, lock.Format, true, nil
+}
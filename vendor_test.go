@@ -0,0 +1,50 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendorWritesPrivateFilesAtomically(t *testing.T) {
+	server := serveDocuments(t, map[string]string{
+		"/project.toml": "[service]\nname = \"tts\"\n",
+	})
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.toml")
+	log := newTestLogger(t)
+
+	require.NoError(t, Vendor(path, log, WithCacheKey(cacheKey)))
+	require.NoError(t, Vendor(path, log, WithCacheKey(cacheKey)))
+
+	entries, readDirErr := os.ReadDir(dir)
+	require.NoError(t, readDirErr)
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+
+		info, infoErr := entry.Info()
+		require.NoError(t, infoErr)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), entry.Name())
+	}
+
+	assert.ElementsMatch(t, []string{"project.toml", "project.toml" + vendorMetadataSuffix}, names)
+
+	copied, readErr := os.ReadFile(path)
+	require.NoError(t, readErr)
+	assert.NotContains(t, string(copied), "tts")
+
+	server.Close()
+
+	var config testConfig
+
+	require.NoError(t, Load(&config, log, WithVendoredCopy(path), WithCacheKey(cacheKey)))
+	assert.Equal(t, "tts", config.Service.Name)
+}
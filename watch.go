@@ -0,0 +1,295 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+// DefaultPollInterval defines how often Watch re-fetches the configuration source.
+const DefaultPollInterval = 30 * time.Second
+
+// Update carries a newly decoded configuration, or the error that prevented loading it.
+// Hash, Source, and LoadedAt describe the document a successful update was decoded from;
+// ChangedKeys lists the dotted key paths that differ from the previous configuration and
+// is empty for the initial update.
+type Update[T any] struct {
+	Config      *T
+	Err         error
+	Hash        string
+	Source      string
+	LoadedAt    time.Time
+	ChangedKeys []string
+
+	// applied, set when a Store consumes the updates, receives whether the update was
+	// applied.
+	applied chan<- error
+}
+
+// WithPollInterval sets how often Watch re-fetches the configuration source.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// watcher holds the state of a running Watch.
+type watcher[T any] struct {
+	updates      chan Update[T]
+	reloads      <-chan struct{}
+	cache        *fetchCache
+	breaker      *circuitBreaker
+	settings     *options
+	logger       *logger.Logger
+	source       string
+	files        []string
+	lastHash     string
+	lastContent  []byte
+	rejectedHash string
+	rejectedErr  error
+}
+
+// Watch loads the configuration into a new T and returns a channel that first delivers
+// that configuration and then a new Update whenever the source document changes or
+// fails to load. Local base files are watched for filesystem events; URLs are polled.
+// A signal set with WithReloadSignals or a ReloadTrigger forces an immediate re-fetch.
+// The channel is closed once ctx is cancelled.
+func Watch[T any](ctx context.Context, logger *logger.Logger, opts ...Option) (<-chan Update[T], error) {
+	settings := newOptions(opts)
+	settings.loadContext = ctx
+	cache := newFetchCache()
+
+	effective, contentErr := loadContent(settings, cache, logger)
+	if contentErr != nil {
+		return nil, contentErr
+	}
+
+	auditErr := auditPermissions(effective.files, (*T)(nil), []string{""}, settings, logger)
+	if auditErr != nil {
+		return nil, auditErr
+	}
+
+	initial, decodeErr := decode[T](effective.content, settings)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	state := &watcher[T]{
+		updates:     make(chan Update[T]),
+		reloads:     reloadRequests(ctx, settings),
+		cache:       cache,
+		breaker:     newCircuitBreaker(settings),
+		settings:    settings,
+		logger:      logger,
+		source:      os.Getenv("PROJECT_TOML"),
+		files:       effective.files,
+		lastHash:    contentHash(effective.content),
+		lastContent: effective.content,
+	}
+	first := state.loaded(initial)
+
+	if effective.baseLocal {
+		fileWatcher, watcherErr := newFileWatcher()
+		if watcherErr != nil {
+			return nil, watcherErr
+		}
+
+		watchDirsErr := state.watchDirectories(fileWatcher)
+		if watchDirsErr != nil {
+			closeErr := fileWatcher.Close()
+			if closeErr != nil {
+				logger.Error("failed to close file watcher: %v", closeErr)
+			}
+
+			return nil, watchDirsErr
+		}
+
+		go func() {
+			defer close(state.updates)
+
+			if state.send(ctx, first) {
+				state.watchFiles(ctx, fileWatcher)
+			}
+		}()
+
+		return state.updates, nil
+	}
+
+	go func() {
+		defer close(state.updates)
+
+		if state.send(ctx, first) {
+			state.poll(ctx)
+		}
+	}()
+
+	return state.updates, nil
+}
+
+// poll re-fetches the configuration on every tick and publishes changes until ctx is done.
+// Requests are conditional on the cached responses, so an unchanged document costs a
+// 304 response; forced reloads bypass them. Ticks are skipped while the circuit breaker
+// is open, but forced reloads still fetch.
+func (w *watcher[T]) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.settings.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		cache := w.cache
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !w.breaker.allow(time.Now()) {
+				continue
+			}
+		case <-w.reloads:
+			cache = nil
+		}
+
+		if !w.refresh(ctx, cache) {
+			return
+		}
+	}
+}
+
+// refresh reloads the configuration, publishes the resulting update when there is
+// something new to report, and records the attempt. When the consumer reports that it
+// could not apply a new configuration, the attempt is recorded as failed and the last
+// hash is cleared, so the next reload offers the configuration again. It reports false
+// once ctx is cancelled.
+func (w *watcher[T]) refresh(ctx context.Context, cache *fetchCache) bool {
+	previousContent := w.lastContent
+
+	update, publish := w.reload(cache)
+	if !publish || update.Err != nil {
+		w.settings.metrics.record(update.Err, false, w.settings.reloadHooks)
+
+		return !publish || w.send(ctx, update)
+	}
+
+	var verdict chan error
+	if w.settings.awaitApply {
+		verdict = make(chan error, 1)
+		update.applied = verdict
+	}
+
+	if !w.send(ctx, update) {
+		return false
+	}
+
+	if verdict != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case applyErr := <-verdict:
+			if applyErr != nil {
+				w.lastHash, w.lastContent = "", previousContent
+				w.settings.metrics.record(applyErr, false, w.settings.reloadHooks)
+
+				return true
+			}
+		}
+	}
+
+	w.settings.metrics.record(nil, true, w.settings.reloadHooks)
+	w.notifyChange(update)
+
+	return true
+}
+
+// reload loads the configuration and returns an update when its hash differs from the
+// last published one, or the error when loading fails. A document that fails to decode or
+// validate is published once and afterwards only counted as a failure, so the previous
+// good configuration stays in effect. The boolean result reports whether to publish.
+func (w *watcher[T]) reload(cache *fetchCache) (Update[T], bool) {
+	effective, contentErr := loadContent(w.settings, cache, w.logger)
+	w.breaker.record(contentErr, time.Now())
+
+	if contentErr != nil {
+		return Update[T]{Err: contentErr}, true
+	}
+
+	w.files = effective.files
+	content := effective.content
+
+	hash := contentHash(content)
+	if hash == w.lastHash {
+		return Update[T]{}, false
+	}
+
+	if hash == w.rejectedHash {
+		return Update[T]{Err: w.rejectedErr}, false
+	}
+
+	auditErr := auditPermissions(effective.files, (*T)(nil), []string{""}, w.settings, w.logger)
+	if auditErr != nil {
+		return Update[T]{Err: auditErr}, true
+	}
+
+	config, decodeErr := decode[T](content, w.settings)
+	if decodeErr != nil {
+		w.rejectedHash, w.rejectedErr = hash, decodeErr
+
+		return Update[T]{Err: decodeErr}, true
+	}
+
+	changed, diffErr := changedKeys(w.lastContent, content)
+	if diffErr != nil {
+		return Update[T]{Err: diffErr}, true
+	}
+
+	w.lastHash = hash
+	w.lastContent = content
+	update := w.loaded(config)
+	update.ChangedKeys = changed
+
+	return update, true
+}
+
+// loaded builds the Update for a successfully decoded configuration.
+func (w *watcher[T]) loaded(config *T) Update[T] {
+	return Update[T]{
+		Config:   config,
+		Hash:     w.lastHash,
+		Source:   w.source,
+		LoadedAt: time.Now(),
+	}
+}
+
+// send delivers update unless ctx is cancelled first, reporting whether it was sent.
+func (w *watcher[T]) send(ctx context.Context, update Update[T]) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.updates <- update:
+		return true
+	}
+}
+
+// decode unmarshals content into a newly allocated T, decrypts its secret fields, and
+// validates the result.
+func decode[T any](content []byte, settings *options) (*T, error) {
+	target := new(T)
+
+	unmarshalErr := unmarshalTOML(content, target)
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to unmarshal TOML: %w", unmarshalErr)
+	}
+
+	revealErr := revealSecrets(target, settings)
+	if revealErr != nil {
+		return nil, revealErr
+	}
+
+	validateErr := validateConfig(target, settings)
+	if validateErr != nil {
+		return nil, validateErr
+	}
+
+	return target, nil
+}
@@ -0,0 +1,213 @@
+package configurator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// quiet is how long the tests wait to be sure that no update is published.
+const quiet = 200 * time.Millisecond
+
+// startWatch starts a Watch of testConfig that stops when the test ends, and returns its
+// updates and the function that stops it early.
+func startWatch(t *testing.T, opts ...Option) (<-chan Update[testConfig], context.CancelFunc) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	updates, watchErr := Watch[testConfig](ctx, newTestLogger(t), opts...)
+	require.NoError(t, watchErr)
+
+	return updates, cancel
+}
+
+// nextUpdate returns the next update published on updates.
+func nextUpdate(t *testing.T, updates <-chan Update[testConfig]) Update[testConfig] {
+	t.Helper()
+
+	select {
+	case update, ok := <-updates:
+		require.True(t, ok, "updates closed")
+
+		return update
+	case <-time.After(eventually):
+		require.FailNow(t, "no update published")
+
+		return Update[testConfig]{}
+	}
+}
+
+// noUpdate fails the test when an update is published within quiet.
+func noUpdate(t *testing.T, updates <-chan Update[testConfig]) {
+	t.Helper()
+
+	select {
+	case update := <-updates:
+		assert.Failf(t, "unexpected update", "%+v", update)
+	case <-time.After(quiet):
+	}
+}
+
+// etagServer serves a replaceable project.toml with an ETag, answering matching
+// conditional requests with 304 Not Modified.
+type etagServer struct {
+	*httptest.Server
+	document    atomic.Value
+	full        atomic.Int32
+	notModified atomic.Int32
+}
+
+// newETagServer starts an etagServer serving document and points PROJECT_TOML at it.
+func newETagServer(t *testing.T, document string) *etagServer {
+	t.Helper()
+
+	server := &etagServer{}
+	server.document.Store(document)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, _ := server.document.Load().(string)
+		etag := `"` + contentHash([]byte(document)) + `"`
+
+		if r.Header.Get("If-None-Match") == etag {
+			server.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		server.full.Add(1)
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(document))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PROJECT_TOML", server.URL+"/project.toml")
+
+	return server
+}
+
+func TestWatchPollsWithConditionalRequests(t *testing.T) {
+	server := newETagServer(t, "[service]\nname = \"first\"\n")
+	metrics := NewReloadMetrics()
+
+	updates, _ := startWatch(t, WithPollInterval(20*time.Millisecond), WithReloadMetrics(metrics))
+	assert.Equal(t, "first", nextUpdate(t, updates).Config.Service.Name)
+
+	require.Eventually(t, func() bool {
+		return server.notModified.Load() >= 3
+	}, eventually, tick)
+	noUpdate(t, updates)
+	assert.Equal(t, int32(1), server.full.Load(), "unchanged polls cost a 304")
+	assert.Zero(t, metrics.Stats().Failures)
+
+	server.document.Store("[service]\nname = \"second\"\n")
+
+	update := nextUpdate(t, updates)
+	require.NoError(t, update.Err)
+	assert.Equal(t, "second", update.Config.Service.Name)
+	assert.Equal(t, int32(2), server.full.Load())
+}
+
+func TestWatchForcedReloadsBypassConditionalRequests(t *testing.T) {
+	server := newETagServer(t, "[service]\nname = \"first\"\n")
+	trigger := NewReloadTrigger()
+
+	updates, _ := startWatch(t, WithPollInterval(time.Hour), WithReloadTrigger(trigger))
+	nextUpdate(t, updates)
+
+	trigger.Trigger()
+
+	require.Eventually(t, func() bool {
+		return server.full.Load() == 2
+	}, eventually, tick)
+	assert.Zero(t, server.notModified.Load())
+	noUpdate(t, updates)
+}
+
+func TestWatchDeliversInitialConfiguration(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"first\"\nport = 8080\n")
+	metrics := NewReloadMetrics()
+
+	updates, _ := startWatch(t, WithPollInterval(time.Hour), WithReloadMetrics(metrics))
+
+	first := nextUpdate(t, updates)
+	require.NoError(t, first.Err)
+	assert.Equal(t, "first", first.Config.Service.Name)
+	assert.Equal(t, 8080, first.Config.Service.Port)
+	assert.Equal(t, contentHash([]byte("[service]\nname = \"first\"\nport = 8080\n")), first.Hash)
+	assert.Equal(t, server.URL+"/project.toml", first.Source)
+	assert.Empty(t, first.ChangedKeys)
+	assert.WithinDuration(t, time.Now(), first.LoadedAt, eventually)
+	assert.Zero(t, metrics.Stats().Attempts)
+}
+
+func TestWatchPublishesChangesOnReload(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"first\"\nport = 8080\n")
+	trigger := NewReloadTrigger()
+	metrics := NewReloadMetrics()
+
+	updates, _ := startWatch(t, WithPollInterval(time.Hour), WithReloadTrigger(trigger), WithReloadMetrics(metrics))
+	nextUpdate(t, updates)
+
+	server.document.Store("[service]\nname = \"second\"\nport = 8080\n")
+	trigger.Trigger()
+
+	second := nextUpdate(t, updates)
+	require.NoError(t, second.Err)
+	assert.Equal(t, "second", second.Config.Service.Name)
+	assert.Equal(t, []string{"service.name"}, second.ChangedKeys)
+	assert.True(t, second.Changed("service"))
+	assert.False(t, second.Changed("nats"))
+
+	trigger.Trigger()
+	noUpdate(t, updates)
+
+	require.Eventually(t, func() bool {
+		return metrics.Stats().Attempts == 2
+	}, eventually, tick)
+	assert.Equal(t, uint64(2), metrics.Stats().Successes)
+}
+
+func TestWatchPublishesLoadFailures(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"first\"\n")
+	trigger := NewReloadTrigger()
+
+	updates, _ := startWatch(t, WithPollInterval(time.Hour), WithReloadTrigger(trigger), WithRetry(RetryPolicy{Attempts: 1}))
+	nextUpdate(t, updates)
+
+	server.Close()
+	trigger.Trigger()
+
+	failed := nextUpdate(t, updates)
+	require.Error(t, failed.Err)
+	assert.Nil(t, failed.Config)
+}
+
+func TestWatchReturnsInitialLoadErrors(t *testing.T) {
+	localDocument(t, "[service\nname = ")
+
+	_, watchErr := Watch[testConfig](context.Background(), newTestLogger(t))
+	require.Error(t, watchErr)
+}
+
+func TestWatchClosesUpdatesWhenCancelled(t *testing.T) {
+	newMutableServer(t, "[service]\nname = \"first\"\n")
+
+	updates, cancel := startWatch(t, WithPollInterval(time.Hour))
+	nextUpdate(t, updates)
+
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		assert.False(t, ok)
+	case <-time.After(eventually):
+		require.FailNow(t, "updates not closed")
+	}
+}
@@ -0,0 +1,123 @@
+package configurator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// webhookSignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed with "sha256=".
+	webhookSignatureHeader = "X-Hub-Signature-256"
+	// webhookSignaturePrefix precedes the hex digest in webhookSignatureHeader.
+	webhookSignaturePrefix = "sha256="
+	// webhookBearerPrefix precedes the shared secret in the Authorization header.
+	webhookBearerPrefix = "Bearer "
+	// maxWebhookBodyBytes caps how much of a webhook request body is read for signing.
+	maxWebhookBodyBytes = 1 << 20
+)
+
+// ReloadTrigger lets external code request an immediate reload of a running Watch.
+type ReloadTrigger struct {
+	requests chan struct{}
+}
+
+// NewReloadTrigger returns a ReloadTrigger to pass to WithReloadTrigger.
+func NewReloadTrigger() *ReloadTrigger {
+	return &ReloadTrigger{requests: make(chan struct{}, 1)}
+}
+
+// Trigger requests a reload without blocking. Requests made while one is already
+// pending are coalesced.
+func (t *ReloadTrigger) Trigger() {
+	select {
+	case t.requests <- struct{}{}:
+	default:
+	}
+}
+
+// WithReloadTrigger makes Watch re-fetch the configuration immediately whenever trigger
+// fires. A trigger should be attached to a single Watch or Store.
+func WithReloadTrigger(trigger *ReloadTrigger) Option {
+	return func(o *options) {
+		o.reloadTrigger = trigger
+	}
+}
+
+// WebhookHandler returns an http.Handler that fires trigger for authenticated POST
+// requests, letting CI or the config host push reloads. A request is authenticated
+// either by an "Authorization: Bearer <secret>" header or by an X-Hub-Signature-256
+// header holding the HMAC-SHA256 of the body keyed with secret.
+func WebhookHandler(secret string, trigger *ReloadTrigger) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		body, readErr := io.ReadAll(io.LimitReader(request.Body, maxWebhookBodyBytes))
+		if readErr != nil {
+			http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+
+			return
+		}
+
+		if !authenticWebhook(request, body, secret) {
+			http.Error(writer, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		trigger.Trigger()
+		writer.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// authenticWebhook reports whether request carries a valid bearer secret or body signature.
+func authenticWebhook(request *http.Request, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	authorization := request.Header.Get("Authorization")
+	if token, found := strings.CutPrefix(authorization, webhookBearerPrefix); found {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	signature, found := strings.CutPrefix(request.Header.Get(webhookSignatureHeader), webhookSignaturePrefix)
+	if !found {
+		return false
+	}
+
+	provided, decodeErr := hex.DecodeString(signature)
+	if decodeErr != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
+// forwardTrigger relays trigger requests into reloads until ctx is done.
+func forwardTrigger(ctx context.Context, trigger *ReloadTrigger, reloads chan<- struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger.requests:
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package configurator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookSecret is the secret the webhook tests share with the handler.
+const webhookSecret = "s3cret"
+
+// signBody returns the X-Hub-Signature-256 value of body under secret.
+func signBody(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// fired reports whether trigger holds a pending reload request, consuming it.
+func fired(trigger *ReloadTrigger) bool {
+	select {
+	case <-trigger.requests:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWebhookHandlerAuthenticatesRequests(t *testing.T) {
+	t.Parallel()
+
+	const body = `{"ref":"refs/heads/main"}`
+
+	tests := map[string]struct {
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		"bearer secret":       {headers: map[string]string{"Authorization": "Bearer " + webhookSecret}, want: http.StatusAccepted},
+		"body signature":      {headers: map[string]string{webhookSignatureHeader: signBody(body, webhookSecret)}, want: http.StatusAccepted},
+		"wrong bearer secret": {headers: map[string]string{"Authorization": "Bearer guess"}, want: http.StatusUnauthorized},
+		"signature by another secret": {
+			headers: map[string]string{webhookSignatureHeader: signBody(body, "guess")}, want: http.StatusUnauthorized,
+		},
+		"signature of another body": {
+			headers: map[string]string{webhookSignatureHeader: signBody(body+" ", webhookSecret)}, want: http.StatusUnauthorized,
+		},
+		"malformed signature": {headers: map[string]string{webhookSignatureHeader: "sha256=zz"}, want: http.StatusUnauthorized},
+		"basic authentication": {
+			headers: map[string]string{"Authorization": "Basic " + webhookSecret}, want: http.StatusUnauthorized,
+		},
+		"no credentials": {want: http.StatusUnauthorized},
+		"GET request": {
+			method: http.MethodGet, headers: map[string]string{"Authorization": "Bearer " + webhookSecret},
+			want: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			trigger := NewReloadTrigger()
+			method := test.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			request := httptest.NewRequest(method, "/reload", strings.NewReader(body))
+			for header, value := range test.headers {
+				request.Header.Set(header, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			WebhookHandler(webhookSecret, trigger).ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.want, recorder.Code)
+			assert.Equal(t, test.want == http.StatusAccepted, fired(trigger))
+		})
+	}
+}
+
+func TestWebhookHandlerRejectsEverythingWithoutSecret(t *testing.T) {
+	t.Parallel()
+
+	trigger := NewReloadTrigger()
+	request := httptest.NewRequest(http.MethodPost, "/reload", strings.NewReader(""))
+	request.Header.Set("Authorization", "Bearer ")
+	request.Header.Set(webhookSignatureHeader, signBody("", ""))
+
+	recorder := httptest.NewRecorder()
+	WebhookHandler("", trigger).ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.False(t, fired(trigger))
+}
+
+func TestReloadTriggerCoalescesRequests(t *testing.T) {
+	t.Parallel()
+
+	trigger := NewReloadTrigger()
+	trigger.Trigger()
+	trigger.Trigger()
+
+	assert.True(t, fired(trigger))
+	assert.False(t, fired(trigger))
+}
+
+func TestWebhookReloadsWatch(t *testing.T) {
+	server := newMutableServer(t, "[service]\nname = \"first\"\n")
+	trigger := NewReloadTrigger()
+
+	updates, _ := startWatch(t, WithPollInterval(time.Hour), WithReloadTrigger(trigger))
+	nextUpdate(t, updates)
+
+	receiver := httptest.NewServer(WebhookHandler(webhookSecret, trigger))
+	t.Cleanup(receiver.Close)
+
+	server.document.Store("[service]\nname = \"pushed\"\n")
+
+	request, newRequestErr := http.NewRequest(http.MethodPost, receiver.URL, strings.NewReader("{}"))
+	require.NoError(t, newRequestErr)
+	request.Header.Set(webhookSignatureHeader, signBody("{}", webhookSecret))
+
+	resp, doErr := http.DefaultClient.Do(request)
+	require.NoError(t, doErr)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	assert.Equal(t, "pushed", nextUpdate(t, updates).Config.Service.Name)
+}
@@ -0,0 +1,149 @@
+package configurator
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// projectFileName is the configuration file name looked for in a workspace when no
+// names are given.
+const projectFileName = "project.toml"
+
+// WorkspaceProject is one configuration file found below a workspace root. Keys lists
+// the sorted dotted paths of every value the file sets.
+type WorkspaceProject struct {
+	Path  string
+	Keys  []string
+	table map[string]any
+}
+
+// DiscoverWorkspace finds the configuration file of every directory below root and
+// reports the keys each one sets. names lists the accepted file names in order of
+// preference, such as project.toml and book-expert.toml, and defaults to project.toml.
+// A directory holding several of them contributes only the first. Hidden directories
+// such as .git are skipped. Projects are ordered by depth and then by path, so a parent
+// directory's file precedes those of its subdirectories.
+func DiscoverWorkspace(root string, names ...string) ([]WorkspaceProject, error) {
+	if len(names) == 0 {
+		names = []string{projectFileName}
+	}
+
+	found := make(map[string]string)
+
+	walkErr := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		rank := slices.Index(names, entry.Name())
+		if rank < 0 {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if current, seen := found[dir]; seen && slices.Index(names, filepath.Base(current)) <= rank {
+			return nil
+		}
+
+		found[dir] = path
+
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("failed to discover workspace %s: %w", root, walkErr)
+	}
+
+	paths := slices.Collect(maps.Values(found))
+	slices.SortFunc(paths, func(a, b string) int {
+		if depth := pathDepth(a) - pathDepth(b); depth != 0 {
+			return depth
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	projects := make([]WorkspaceProject, 0, len(paths))
+
+	for _, path := range paths {
+		project, readErr := readWorkspaceProject(path)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to discover workspace %s: %w", root, readErr)
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, nil
+}
+
+// MergeWorkspace combines the projects into a single TOML document, merging each one
+// over those before it.
+func MergeWorkspace(projects []WorkspaceProject, mergeOptions MergeOptions) ([]byte, error) {
+	combined := make(map[string]any)
+
+	for _, project := range projects {
+		combined = Merge(combined, project.table, mergeOptions)
+	}
+
+	content, marshalErr := toml.Marshal(combined)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to encode workspace configuration: %w", marshalErr)
+	}
+
+	return content, nil
+}
+
+// readWorkspaceProject parses the configuration file at path.
+func readWorkspaceProject(path string) (WorkspaceProject, error) {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return WorkspaceProject{}, fmt.Errorf("failed to read %s: %w", path, readErr)
+	}
+
+	table, parseErr := parseTable(content, newOptions(nil))
+	if parseErr != nil {
+		return WorkspaceProject{}, fmt.Errorf("%s: %w", path, parseErr)
+	}
+
+	var keys []string
+
+	leafKeys("", table, &keys)
+	slices.Sort(keys)
+
+	return WorkspaceProject{Path: path, Keys: keys, table: table}, nil
+}
+
+// leafKeys appends to keys the dotted path of every non-table value below prefix.
+func leafKeys(prefix string, table map[string]any, keys *[]string) {
+	for key, value := range table {
+		path := joinKey(prefix, key)
+
+		if nested, isTable := value.(map[string]any); isTable {
+			leafKeys(path, nested, keys)
+
+			continue
+		}
+
+		*keys = append(*keys, path)
+	}
+}
+
+// pathDepth returns the number of directories in path.
+func pathDepth(path string) int {
+	return strings.Count(filepath.ToSlash(path), "/")
+}
@@ -0,0 +1,107 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeWorkspace writes files, keyed by slash-separated path, below a new directory and
+// returns it.
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	return root
+}
+
+// projectPaths returns the paths of projects relative to root.
+func projectPaths(t *testing.T, root string, projects []WorkspaceProject) []string {
+	t.Helper()
+
+	paths := make([]string, 0, len(projects))
+
+	for _, project := range projects {
+		relative, relErr := filepath.Rel(root, project.Path)
+		require.NoError(t, relErr)
+
+		paths = append(paths, filepath.ToSlash(relative))
+	}
+
+	return paths
+}
+
+func TestDiscoverWorkspaceFindsProjectsByDepth(t *testing.T) {
+	t.Parallel()
+
+	root := writeWorkspace(t, map[string]string{
+		"project.toml":              "[nats]\nurl = \"nats://localhost\"\n",
+		"services/tts/project.toml": "[service]\nname = \"tts\"\nport = 8080\n",
+		"services/asr/project.toml": "[service]\nname = \"asr\"\n",
+		"tools/project.toml":        "[nats]\nurl = \"nats://tools\"\n",
+		".git/project.toml":         "hidden = true\n",
+		"services/notes.toml":       "ignored = true\n",
+	})
+
+	projects, discoverErr := DiscoverWorkspace(root)
+	require.NoError(t, discoverErr)
+	assert.Equal(t, []string{
+		"project.toml", "tools/project.toml", "services/asr/project.toml", "services/tts/project.toml",
+	}, projectPaths(t, root, projects))
+	assert.Equal(t, []string{"service.name", "service.port"}, projects[3].Keys)
+
+	merged, mergeErr := MergeWorkspace(projects, MergeOptions{})
+	require.NoError(t, mergeErr)
+
+	var combined map[string]any
+
+	require.NoError(t, toml.Unmarshal(merged, &combined))
+	assert.Equal(t, map[string]any{"url": "nats://tools"}, combined["nats"])
+	assert.Equal(t, map[string]any{"name": "tts", "port": int64(8080)}, combined["service"])
+}
+
+func TestDiscoverWorkspaceReportsInvalidProjects(t *testing.T) {
+	t.Parallel()
+
+	root := writeWorkspace(t, map[string]string{"broken/project.toml": "[service\n"})
+
+	_, discoverErr := DiscoverWorkspace(root)
+	require.ErrorContains(t, discoverErr, "broken")
+
+	_, missingErr := DiscoverWorkspace(filepath.Join(root, "missing"))
+	require.ErrorIs(t, missingErr, os.ErrNotExist)
+}
+
+func TestDiscoverWorkspaceAcceptsAlternativeNames(t *testing.T) {
+	t.Parallel()
+
+	root := writeWorkspace(t, map[string]string{
+		"book-expert.toml":            "level = \"root\"\n",
+		"tts/project.toml":            "level = \"preferred\"\n",
+		"tts/.bookexpert.toml":        "level = \"fallback\"\n",
+		"asr/.bookexpert.toml":        "level = \"only\"\n",
+		"ocr/config.toml":             "level = \"not accepted\"\n",
+		"ocr/nested/book-expert.toml": "level = \"nested\"\n",
+	})
+
+	projects, discoverErr := DiscoverWorkspace(root, "project.toml", "book-expert.toml", ".bookexpert.toml")
+	require.NoError(t, discoverErr)
+	assert.Equal(t, []string{
+		"book-expert.toml", "asr/.bookexpert.toml", "tts/project.toml", "ocr/nested/book-expert.toml",
+	}, projectPaths(t, root, projects))
+
+	defaults, defaultErr := DiscoverWorkspace(root)
+	require.NoError(t, defaultErr)
+	assert.Equal(t, []string{"tts/project.toml"}, projectPaths(t, root, defaults))
+}